@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/geteduroam/linux-app/internal/discovery"
+	"github.com/geteduroam/linux-app/internal/provider"
+)
+
+// profileOutput is the output for a single profile
+// The JSON names are stable such that scripts can depend on them
+type profileOutput struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Flow                 string `json:"flow"`
+	EapConfigEndpoint    string `json:"eapconfig_endpoint,omitempty"`
+	MobileConfigEndpoint string `json:"mobileconfig_endpoint,omitempty"`
+	LetsWifiEndpoint     string `json:"letswifi_endpoint,omitempty"`
+	WebviewEndpoint      string `json:"webview_endpoint,omitempty"`
+}
+
+// providerOutput is the output for a single provider
+// The JSON names are stable such that scripts can depend on them
+type providerOutput struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Country  string          `json:"country"`
+	Profiles []profileOutput `json:"profiles"`
+}
+
+// newProviderOutput creates the output for a provider
+func newProviderOutput(p *provider.Provider) providerOutput {
+	po := providerOutput{
+		ID:       p.ID,
+		Name:     p.Name.Get(),
+		Country:  p.Country,
+		Profiles: []profileOutput{},
+	}
+	for _, prof := range p.Profiles {
+		po.Profiles = append(po.Profiles, profileOutput{
+			ID:                   prof.ID,
+			Name:                 prof.Name.Get(),
+			Flow:                 prof.Flow().String(),
+			EapConfigEndpoint:    prof.EapConfigEndpoint,
+			MobileConfigEndpoint: prof.MobileConfigEndpoint,
+			LetsWifiEndpoint:     prof.LetsWifiEndpoint,
+			WebviewEndpoint:      prof.WebviewEndpoint,
+		})
+	}
+	return po
+}
+
+// endpoint returns the endpoint that is used for the flow of the profile
+func (po profileOutput) endpoint() string {
+	for _, ep := range []string{po.EapConfigEndpoint, po.LetsWifiEndpoint, po.WebviewEndpoint} {
+		if ep != "" {
+			return ep
+		}
+	}
+	return ""
+}
+
+// writeJSON writes the value as indented JSON
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// discoveryProviders gets the providers from discovery
+func discoveryProviders(mirrors string) (*provider.Providers, error) {
+	ctx, stop := interruptible()
+	defer stop()
+	return cachedProviders(ctx, discovery.NewCache(mirrors))
+}
+
+// cachedProviders gets the providers from the cache
+// If discovery could not be updated, the previously cached list is used unless the context was cancelled
+func cachedProviders(ctx context.Context, c *discovery.Cache) (*provider.Providers, error) {
+	prov, err := c.Providers(ctx)
+	if err == nil {
+		return prov, nil
+	}
+	// the user interrupted, do not continue with the cached list
+	if len(*prov) == 0 || ctx.Err() != nil {
+		return nil, err
+	}
+	slog.Warn("Failed to update providers from discovery, using the cached list", "error", err)
+	fmt.Fprintf(os.Stderr, "Failed to update providers from discovery, using the previously cached list: %v\n", err)
+	return prov, nil
+}
+
+// listProviders prints the providers from discovery, filtered by a search and country
+func listProviders(args []string, mirrors string) error {
+	fs := flag.NewFlagSet("list-providers", flag.ContinueOnError)
+	search := fs.String("search", "", "Only list the organizations that match the search")
+	country := fs.String("country", "", "Only list the organizations from this country code, e.g. NL")
+	jsonf := fs.Bool("json", false, "Output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	prov, err := discoveryProviders(mirrors)
+	if err != nil {
+		return err
+	}
+	// FilterSort sorts the most relevant providers last
+	filtered := slices.Clone(*prov.FilterSort(*search))
+	slices.Reverse(filtered)
+
+	out := []providerOutput{}
+	for idx := range filtered {
+		p := &filtered[idx]
+		if *country != "" && !strings.EqualFold(p.Country, *country) {
+			continue
+		}
+		out = append(out, newProviderOutput(p))
+	}
+	if *jsonf {
+		return writeJSON(os.Stdout, out)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCOUNTRY\tPROFILES\tNAME")
+	for _, p := range out {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", p.ID, p.Country, len(p.Profiles), p.Name)
+	}
+	return tw.Flush()
+}
+
+// showProvider prints a single provider from discovery with its profiles
+func showProvider(args []string, mirrors string) error {
+	fs := flag.NewFlagSet("show-provider", flag.ContinueOnError)
+	jsonf := fs.Bool("json", false, "Output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("show-provider needs exactly one provider ID")
+	}
+	prov, err := discoveryProviders(mirrors)
+	if err != nil {
+		return err
+	}
+	p, err := prov.ByID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	out := newProviderOutput(p)
+	if *jsonf {
+		return writeJSON(os.Stdout, out)
+	}
+	fmt.Printf("ID: %s\nName: %s\nCountry: %s\n\n", out.ID, out.Name, out.Country)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE ID\tFLOW\tNAME\tENDPOINT")
+	for _, prof := range out.Profiles {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", prof.ID, prof.Flow, prof.Name, prof.endpoint())
+	}
+	return tw.Flush()
+}
+
+// command runs a subcommand with the arguments
+// It returns the exit code
+func command(args []string, mirrors string) int {
+	var err error
+	switch args[0] {
+	case "list-providers":
+		err = listProviders(args[1:], mirrors)
+	case "show-provider":
+		err = showProvider(args[1:], mirrors)
+	default:
+		err = fmt.Errorf("unknown command: %q", args[0])
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/geteduroam/linux-app/internal/discovery"
+	"github.com/geteduroam/linux-app/internal/provider"
+)
+
+func TestCachedProviders(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	// discovery cannot be updated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		cached provider.Providers
+		cancel bool
+		want   string
+		err    bool
+	}{
+		// the failed update keeps the previously cached list
+		{cached: provider.Providers{{ID: "provider_one"}}, want: "provider_one"},
+		// without a cached list there is nothing to use
+		{err: true},
+		// the user interrupted, do not continue with the cached list
+		{cached: provider.Providers{{ID: "provider_one"}}, cancel: true, err: true},
+	}
+	for _, c := range cases {
+		ctx, cancel := context.WithCancel(context.Background())
+		if c.cancel {
+			cancel()
+		}
+		cache := discovery.NewCache(srv.URL + "/discovery.json")
+		cache.Client = srv.Client()
+		cache.Cached.Value.Providers = c.cached
+		got, err := cachedProviders(ctx, cache)
+		cancel()
+		if c.err {
+			if err == nil {
+				t.Fatalf("expected an error, got providers: %v", *got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed getting the cached providers: %v", err)
+		}
+		if len(*got) != 1 || (*got)[0].ID != c.want {
+			t.Fatalf("providers not equal, want: %v, got: %v", c.want, *got)
+		}
+	}
+}
@@ -3,10 +3,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strconv"
 	"strings"
 	"syscall"
@@ -19,6 +21,7 @@ import (
 	"github.com/geteduroam/linux-app/internal/clientver"
 	"github.com/geteduroam/linux-app/internal/discovery"
 	"github.com/geteduroam/linux-app/internal/handler"
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/logwrap"
 	"github.com/geteduroam/linux-app/internal/network"
 	"github.com/geteduroam/linux-app/internal/notification"
@@ -35,6 +38,12 @@ func IsTerminal() bool {
 	return err == nil
 }
 
+// interruptible returns a context that is cancelled when the user interrupts the CLI with Ctrl+C
+// The stop function must be called when the request is done, after which Ctrl+C exits the CLI again, e.g. when asking for input
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt)
+}
+
 // askSecret is a tweak of thee 'ask' function that uses golang.org/x/term to read a secret securely
 // The prompt is the text to show e.g. "enter something: "
 // Validator is the function that checks if the secret is valid
@@ -76,10 +85,11 @@ func ask(prompt string, validator func(input string) bool) string {
 }
 
 // filteredOrganizations gets the providers as filtered by the user
-func filteredOrganizations(orgs *provider.Providers, q string) (f *provider.Providers) {
+// It returns the filtered providers and the search that was used
+func filteredOrganizations(orgs *provider.Providers, q string) (f *provider.Providers, x string) {
 	for {
 		empties := 0
-		x := ask(q, func(x string) bool {
+		x = ask(q, func(x string) bool {
 			if len(x) == 0 {
 				// File managers are very insane
 				// They somehow keep entering empty inputs
@@ -101,7 +111,18 @@ func filteredOrganizations(orgs *provider.Providers, q string) (f *provider.Prov
 		}
 		fmt.Fprintf(os.Stderr, "No organizations found with search term: %v. Please try again\n", x)
 	}
-	return f
+	return f, x
+}
+
+// matchDescription returns the name of the provider with an explanation why it matched the search
+// The explanation is only added if the match was not on the name
+func matchDescription(p *provider.Provider, search string) string {
+	name := p.Name.Get()
+	m := p.Match(search)
+	if m.Field == provider.NameField {
+		return name
+	}
+	return fmt.Sprintf("%s (matched %s: %s)", name, m.Field, m.Value)
 }
 
 // validateRange validates if the input is in the range of 1-n (inclusive)
@@ -125,22 +146,21 @@ func organization(orgs *provider.Providers) *provider.Provider {
 		slog.Warn("Could not get height")
 		h = 10
 	}
-	f := orgs
-	f = filteredOrganizations(f, "Please enter your organization (e.g. SURF): ")
+	f, search := filteredOrganizations(orgs, "Please enter your organization (e.g. SURF or @example.edu): ")
 	for {
 		if len(*f) > h-3 {
 			for _, c := range *f {
-				fmt.Printf("%s\n", c.Name.Get())
+				fmt.Printf("%s\n", matchDescription(&c, search))
 			}
 			fmt.Println("\nList is long...")
-			f = filteredOrganizations(f, "Please refine your search: ")
+			f, search = filteredOrganizations(f, "Please refine your search: ")
 		} else {
 			break
 		}
 	}
 	fmt.Println("\nFound the following matches: ")
 	for n, c := range *f {
-		fmt.Printf("[%d] %s\n", n+1, c.Name.Get())
+		fmt.Printf("[%d] %s\n", n+1, matchDescription(&c, search))
 	}
 	input := ask("\nPlease enter a choice for the organisation: ", func(input string) bool {
 		return validateRange(input, len(*f))
@@ -175,6 +195,77 @@ func profile(profiles []provider.Profile) *provider.Profile {
 	return &profiles[r-1]
 }
 
+// identityProvider asks the user to choose one of the identity providers from the EAP metadata
+// It returns the index of the chosen candidate
+func identityProvider(candidates []network.Network) (int, error) {
+	if !IsTerminal() {
+		return 0, errors.New("the EAP metadata contains multiple organizations, run the CLI in a terminal to choose one")
+	}
+	fmt.Println("The EAP metadata contains the following organizations: ")
+	for n, c := range candidates {
+		pi := c.ProviderInfo()
+		desc := fmt.Sprintf("%s (%s)", pi.Name, c.Method())
+		if pi.Description != "" {
+			desc += " - " + pi.Description
+		}
+		if position != nil {
+			if _, d, ok := pi.Nearest(*position); ok {
+				desc += fmt.Sprintf(", %.1f km away", d)
+			}
+		}
+		fmt.Printf("[%d] %s\n", n+1, desc)
+	}
+	input := ask("Please enter a choice for the organization: ", func(input string) bool {
+		return validateRange(input, len(candidates))
+	})
+	r, err := strconv.ParseInt(input, 10, 32)
+	// This can't happen because we already validated that this can be parsed
+	if err != nil {
+		panic(err)
+	}
+	return int(r - 1), nil
+}
+
+// authMethod asks the user to choose one of the authentication methods that the identity provider offers
+// Without a terminal the preferred method is used
+// It returns the index of the chosen candidate
+func authMethod(candidates []network.Network) (int, error) {
+	if !IsTerminal() {
+		return 0, nil
+	}
+	fmt.Println("The profile can be used with the following authentication methods, in order of preference: ")
+	for n, c := range candidates {
+		fmt.Printf("[%d] %s\n", n+1, c.MethodName())
+	}
+	input := ask("Please enter a choice for the authentication method: ", func(input string) bool {
+		return validateRange(input, len(candidates))
+	})
+	r, err := strconv.ParseInt(input, 10, 32)
+	// This can't happen because we already validated that this can be parsed
+	if err != nil {
+		panic(err)
+	}
+	return int(r - 1), nil
+}
+
+// askMedium asks the user whether to add the wireless networks, the wired networks or both
+func askMedium(pi network.ProviderInfo) network.Medium {
+	media := []network.Medium{network.MediumBoth, network.MediumWireless, network.MediumWired}
+	fmt.Printf("The profile of %s can be used for wireless and wired networks\n", pi.Name)
+	fmt.Println("[1] Wireless and wired")
+	fmt.Println("[2] Wireless only")
+	fmt.Println("[3] Wired only")
+	input := ask("Please enter a choice for where to add the profile: ", func(input string) bool {
+		return validateRange(input, len(media))
+	})
+	r, err := strconv.ParseInt(input, 10, 32)
+	// This can't happen because we already validated that this can be parsed
+	if err != nil {
+		panic(err)
+	}
+	return media[r-1]
+}
+
 // askUsername asks the user for the username
 // p is the prefix for which the username must start
 // s is the suffix for which the username must end
@@ -234,6 +325,13 @@ func askPassword() string {
 	return password1
 }
 
+// position is the position of the user that is used to print the nearest campus
+// It is nil if the position is not known
+var position *network.Location
+
+// pmf is the protected management frames policy that overrides the policy derived from the EAP config
+var pmf network.PMF
+
 func printProviderInfo(pi network.ProviderInfo) {
 	fmt.Println("Organization info:")
 	fmt.Println(" Title:", pi.Name)
@@ -249,6 +347,11 @@ func printProviderInfo(pi network.ProviderInfo) {
 	if pi.Helpdesk.Web != "" {
 		fmt.Println(" Helpdesk URL:", pi.Helpdesk.Web)
 	}
+	if position != nil {
+		if l, d, ok := pi.Nearest(*position); ok {
+			fmt.Printf(" Nearest campus: %s (%.1f km)\n", l, d)
+		}
+	}
 }
 
 // askCredentials asks the user for credentials
@@ -260,6 +363,12 @@ func askCredentials(c network.Credentials, pi network.ProviderInfo) (string, str
 	if c.Username == "" {
 		username = askUsername(c.Prefix, c.Suffix)
 	}
+	// a one-time token is not saved, NetworkManager asks for it when connecting
+	if c.OneTimeToken {
+		fmt.Println("This profile uses one-time tokens, NetworkManager asks for a new token every time you connect")
+		fmt.Println("This needs a NetworkManager secret agent, e.g. your desktop environment or \"nmcli --ask connection up\", without one the connection cannot be activated")
+		return username, "", nil
+	}
 	if c.Password == "" {
 		password = askPassword()
 	}
@@ -301,11 +410,26 @@ func askCertificate(cert string, pass string, pi network.ProviderInfo) (string,
 	return cert, pass, nil
 }
 
+// wiredNotice tells the user how to activate the wired connections as these are not activated automatically
+func wiredNotice(ids []string) {
+	fmt.Println("\nThe wired connections are not activated automatically, as they would delay every wired network that does not use 802.1X")
+	fmt.Println("When you are connected to a wired network of your organization, activate them in the network settings or with:")
+	for _, id := range ids {
+		fmt.Printf("  nmcli connection up %q\n", id)
+	}
+}
+
 // file does the flow when the file has been obtained
-func file(metadata []byte) (*time.Time, *time.Time, error) {
+func file(o *options, metadata []byte) (*time.Time, *time.Time, error) {
 	h := handler.Handlers{
-		CredentialsH: askCredentials,
-		CertificateH: askCertificate,
+		CredentialsH: o.credentials,
+		CertificateH: o.certificate,
+		ProviderH:    identityProvider,
+		MediumH:      o.media,
+		MethodH:      authMethod,
+		Method:       o.method,
+		PMF:          pmf,
+		WiredH:       wiredNotice,
 	}
 
 	// Configure the network further.
@@ -314,21 +438,24 @@ func file(metadata []byte) (*time.Time, *time.Time, error) {
 }
 
 // direct does the handling for the direct flow
-func direct(p *provider.Profile) {
-	config, err := p.EAPDirect()
+func direct(o *options, p *provider.Profile) (*time.Time, *time.Time) {
+	ctx, stop := interruptible()
+	config, err := p.EAPDirect(ctx)
+	stop()
 	if err != nil {
 		slog.Error("Could not obtain eap config", "error", err)
 		fmt.Printf("Could not obtain eap config %v\n", err)
 		os.Exit(1)
 	}
 
-	// we can ignore the validity because this does not use a client cert
-	_, _, err = file(config)
+	// the validity is the ValidUntil of the metadata or the expiry of a client certificate
+	vBeg, vEnd, err := file(o, config)
 	if err != nil {
 		slog.Error("Failed to configure the connection using the metadata", "error", err)
 		fmt.Printf("Failed to configure the connection using the metadata %v\n", err)
 		os.Exit(1)
 	}
+	return vBeg, vEnd
 }
 
 // redirect does the handling for the redirect flow
@@ -349,17 +476,19 @@ func redirect(p *provider.Profile) {
 }
 
 // oauth does the handling for the OAuth flow
-func oauth(p *provider.Profile) (*time.Time, *time.Time) {
-	config, err := p.EAPOAuth(context.Background(), func(url string) {
+func oauth(o *options, p *provider.Profile) (*time.Time, *time.Time) {
+	ctx, stop := interruptible()
+	config, err := p.EAPOAuth(ctx, func(url string) {
 		fmt.Println("Your browser has been opened to authorize the client")
 		fmt.Println("Or copy and paste the following url:", url)
 	})
+	stop()
 	if err != nil {
 		slog.Error("Could not obtain eap config with OAuth", "error", err)
 		os.Exit(1)
 	}
 
-	vBeg, vEnd, err := file(config)
+	vBeg, vEnd, err := file(o, config)
 	if err != nil {
 		slog.Error("Failed to configure the connection using the OAuth metadata", "error", err)
 		fmt.Printf("Failed to configure the connection using the OAuth metadata %v\n", err)
@@ -368,14 +497,14 @@ func oauth(p *provider.Profile) (*time.Time, *time.Time) {
 	return vBeg, vEnd
 }
 
-func doLocal(filename string) (*time.Time, *time.Time) {
+func doLocal(o *options, filename string) (*time.Time, *time.Time) {
 	b, err := os.ReadFile(filename)
 	if err != nil {
 		slog.Error("Failed to read local file", "error", err)
 		fmt.Printf("Failed to read local file %v\n", err)
 		os.Exit(1)
 	}
-	vBeg, vEnd, err := file(b)
+	vBeg, vEnd, err := file(o, b)
 	if err != nil {
 		slog.Error("Failed to configure the connection using the metadata", "error", err)
 		fmt.Printf("Failed to configure the connection using the metadata %v\n", err)
@@ -384,43 +513,54 @@ func doLocal(filename string) (*time.Time, *time.Time) {
 	return vBeg, vEnd
 }
 
-func chosenProvider(chosen *provider.Provider) (*time.Time, *time.Time) {
-	p := profile(chosen.Profiles)
+func chosenProvider(o *options, chosen *provider.Provider) (*time.Time, *time.Time) {
+	p, err := o.profile(chosen)
+	if err != nil {
+		slog.Error("Failed to get the profile", "error", err)
+		fmt.Fprintf(os.Stderr, "Failed to get the profile: %v\n", err)
+		os.Exit(1)
+	}
 
 	// TODO: This switch statement should probably be moved to the profile code
 	// By providing an "EAP" method on profile
 	switch p.Flow() {
 	case provider.DirectFlow:
-		direct(p)
+		return direct(o, p)
 	case provider.RedirectFlow:
 		redirect(p)
 	case provider.OAuthFlow:
-		return oauth(p)
+		return oauth(o, p)
 	}
 	return nil, nil
 }
 
-func doDiscovery() (*time.Time, *time.Time) {
-	c := discovery.NewCache()
-	prov, err := c.Providers()
+func doDiscovery(o *options, mirrors string) (*time.Time, *time.Time) {
+	prov, err := discoveryProviders(mirrors)
 	if err != nil {
 		slog.Error("Failed to get providers from discovery", "error", err)
 		fmt.Printf("Failed to get providers from discovery %v\n", err)
 		os.Exit(1)
 	}
 
-	chosen := organization(prov)
-	return chosenProvider(chosen)
+	chosen, err := o.provider(prov)
+	if err != nil {
+		slog.Error("Failed to get the provider", "error", err)
+		fmt.Fprintf(os.Stderr, "Failed to get the provider: %v\n", err)
+		os.Exit(1)
+	}
+	return chosenProvider(o, chosen)
 }
 
-func doURL(url string) (*time.Time, *time.Time) {
-	prov, err := provider.Custom(context.Background(), url)
+func doURL(o *options, url string) (*time.Time, *time.Time) {
+	ctx, stop := interruptible()
+	prov, err := provider.Custom(ctx, url)
+	stop()
 	if err != nil {
 		slog.Error("Failed to get EAP metadata from URL", "error", err)
 		fmt.Printf("Failed to get EAP metadata from URL %v\n", err)
 		os.Exit(1)
 	}
-	return chosenProvider(prov)
+	return chosenProvider(o, prov)
 }
 
 const usage = `Usage of %s:
@@ -428,10 +568,31 @@ const usage = `Usage of %s:
   --version                 Prints version information
   -v                        Verbose
   -d, --debug               Debug
+  --discovery-url=<urls>    Comma separated list of discovery mirrors to try in order (default: $%s or %s)
+  --country=<code>          The country code, e.g. NL, of which organizations are shown first (default: from the region of the language)
+  --lang=<languages>        The languages, e.g. nl_NL or nl,en, in which names and descriptions are shown (default: from the "language" config key or $LC_ALL, $LC_MESSAGES, $LANG and $LANGUAGE)
+  --location=<lat,lon>      Your position, e.g. 52.0,4.36, to show the nearest campus of the organization (default: from the "location" config key)
+  --pmf=<policy>            The protected management frames policy for Wi-Fi networks: default, disable, optional or required (default: from the "pmf" config key, otherwise derived from the profile)
   One of:
   -l <file>, --local=<file> The path to a local EAP metadata file
   -u <url>, --url=<url>     The URL where an EAP metadata file or Let's Wifi portal is hosted
 
+  To run without prompts, e.g. in provisioning scripts. Without a terminal, the ones that are needed for the profile are required:
+  --provider-id=<id>        The ID of the organization in discovery
+  --profile-id=<id>         The ID of the profile of the organization
+  --username=<username>     The username for profiles that need credentials
+  --password-file=<file>    The path to a file containing the password for profiles that need credentials
+  --pkcs12=<file>           The path to a PKCS12 client certificate for profiles that need a certificate
+  --passphrase-file=<file>  The path to a file containing the passphrase of the client certificate
+  --medium=<medium>         Where to add profiles that support wireless and wired networks: wireless, wired or both (default: ask, or both without a terminal)
+  --method=<method>         The authentication method to use when a profile offers multiple, e.g. ttls-pap or peap (default: ask, or the preferred one without a terminal)
+
+  Commands:
+  list-providers [--search=<search>] [--country=<code>] [--json]
+                            Lists the organizations from discovery, optionally filtered by a search and a country code
+  show-provider [--json] <id>
+                            Shows the organization with the ID and its profiles, including their flow and endpoints
+
   This CLI binary is used to add an eduroam connection profile with integration using NetworkManager.
 
   Log file location: %s
@@ -444,6 +605,12 @@ func main() {
 	var debug bool
 	var local string
 	var url string
+	var discoveryURL string
+	var country string
+	var langf string
+	var location string
+	var pmff string
+	var o options
 	program := fmt.Sprintf("%s-cli", variant.DisplayName)
 	lpath, err := logwrap.Location(program)
 	if err != nil {
@@ -459,7 +626,20 @@ func main() {
 	flag.StringVar(&local, "l", "", "The path to a local EAP metadata file")
 	flag.StringVar(&url, "url", "", "Enter a URL to get the EAP metadata from")
 	flag.StringVar(&url, "u", "", "Enter a URL to get the EAP metadata from")
-	flag.Usage = func() { fmt.Printf(usage, program, lpath) }
+	flag.StringVar(&discoveryURL, "discovery-url", "", "Comma separated list of discovery mirrors")
+	flag.StringVar(&country, "country", "", "The country of which organizations are shown first")
+	flag.StringVar(&langf, "lang", "", "The languages in which names and descriptions are shown")
+	flag.StringVar(&location, "location", "", "Your position as latitude,longitude")
+	flag.StringVar(&pmff, "pmf", "", "The protected management frames policy: default, disable, optional or required")
+	flag.StringVar(&o.providerID, "provider-id", "", "The ID of the organization in discovery")
+	flag.StringVar(&o.profileID, "profile-id", "", "The ID of the profile of the organization")
+	flag.StringVar(&o.username, "username", "", "The username for profiles that need credentials")
+	flag.StringVar(&o.passwordFile, "password-file", "", "The path to a file containing the password")
+	flag.StringVar(&o.pkcs12, "pkcs12", "", "The path to a PKCS12 client certificate")
+	flag.StringVar(&o.medium, "medium", "", "Where to add profiles that support wireless and wired networks: wireless, wired or both")
+	flag.StringVar(&o.method, "method", "", "The authentication method to use when a profile offers multiple, e.g. ttls-pap or peap")
+	flag.StringVar(&o.passphraseFile, "passphrase-file", "", "The path to a file containing the passphrase of the client certificate")
+	flag.Usage = func() { fmt.Printf(usage, program, discovery.EnvURLs, variant.DiscoveryURL, lpath) }
 	flag.Parse()
 	if help {
 		flag.Usage()
@@ -469,11 +649,52 @@ func main() {
 		utilsx.IsVerbose = true
 	}
 	logwrap.Initialize(program, debug)
+	if err := lang.Configure(langf); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --lang flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
 	if versionf {
 		fmt.Println(clientver.Get())
 		return
 	}
-	if !IsTerminal() {
+	if country != "" {
+		if err := provider.SetCountry(country); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --country flag: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	position, err = handler.Position(location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --location flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	pmf, err = handler.PMFPolicy(pmff)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --pmf flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if o.medium != "" {
+		if _, err := network.ParseMedium(o.medium); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --medium flag: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	// the subcommands only print and can be used in scripts
+	if flag.NArg() > 0 {
+		os.Exit(command(flag.Args(), discoveryURL))
+	}
+
+	// provisioning scripts do not run in a terminal
+	if !IsTerminal() && !o.unattended() {
 		msg := "Not starting the CLI as it is not run in a terminal. You might want to install the GUI: https://github.com/geteduroam/linux-app/releases"
 		slog.Error(msg)
 		err := notification.Send(msg)
@@ -493,11 +714,11 @@ func main() {
 	var vEnd *time.Time
 	switch {
 	case local != "":
-		vBeg, vEnd = doLocal(local)
+		vBeg, vEnd = doLocal(&o, local)
 	case url != "":
-		vBeg, vEnd = doURL(url)
+		vBeg, vEnd = doURL(&o, url)
 	default:
-		vBeg, vEnd = doDiscovery()
+		vBeg, vEnd = doDiscovery(&o, discoveryURL)
 	}
 	fmt.Printf("\nThe %s profile has been added to NetworkManager\n", variant.ProfileName)
 	if vEnd == nil {
@@ -512,7 +733,8 @@ func main() {
 			fmt.Printf("And you can start using the profile in: %s\n", utilsx.DeltaTime(delta, "", ""))
 		}
 	}
-	if !notification.HasDaemonSupport() {
+	// we cannot ask to enable notifications without a terminal
+	if !notification.HasDaemonSupport() || !IsTerminal() {
 		return
 	}
 	in := ask("Do you want to enable notifications that warn for expiry of the profile (requires systemd and notify-send) (y/n)?: ", func(msg string) bool {
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/geteduroam/linux-app/internal/network"
+	"github.com/geteduroam/linux-app/internal/provider"
+)
+
+// options are the flags that pre-fill the choices of the user
+// If every needed option is given, the CLI can run unattended
+type options struct {
+	// providerID is the ID of the provider in discovery
+	providerID string
+	// profileID is the ID of the profile of the provider
+	profileID string
+	// username is the username for credentials based profiles
+	username string
+	// passwordFile is the path to a file containing the password for credentials based profiles
+	passwordFile string
+	// pkcs12 is the path to a PKCS12 client certificate for TLS profiles
+	pkcs12 string
+	// passphraseFile is the path to a file containing the passphrase for the PKCS12 client certificate
+	passphraseFile string
+	// medium is where to install profiles that support wireless and wired networks: wireless, wired or both
+	medium string
+	// method is the name of the authentication method to use when a profile offers multiple, e.g. ttls-pap or peap
+	method string
+}
+
+// unattended returns whether or not any of the options were given
+// In that case the CLI is probably run from a script instead of a terminal
+func (o *options) unattended() bool {
+	return *o != options{}
+}
+
+// errMissing returns the error for a flag that is needed as the user cannot be asked without a terminal
+func errMissing(flag string, what string) error {
+	return fmt.Errorf("the --%s flag is needed for %s as the CLI is not run in a terminal", flag, what)
+}
+
+// readSecret reads a secret from a file
+// A trailing newline is removed as that is added by most editors and `echo`
+func readSecret(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// provider gets the provider using the provider ID flag
+// If the flag is not given, the user is asked to choose one
+// Without a terminal the flag is required
+func (o *options) provider(orgs *provider.Providers) (*provider.Provider, error) {
+	if o.providerID == "" {
+		if !IsTerminal() {
+			return nil, errMissing("provider-id", "the organization")
+		}
+		return organization(orgs), nil
+	}
+	return orgs.ByID(o.providerID)
+}
+
+// profile gets the profile using the profile ID flag
+// If the flag is not given, the user is asked to choose one
+// Without a terminal the flag is required if the provider has multiple profiles
+func (o *options) profile(p *provider.Provider) (*provider.Profile, error) {
+	if o.profileID == "" {
+		if len(p.Profiles) > 1 && !IsTerminal() {
+			return nil, errMissing("profile-id", "the profile")
+		}
+		return profile(p.Profiles), nil
+	}
+	return p.ProfileByID(o.profileID)
+}
+
+// credentials fills in the credentials from the username and password file flags
+// The credentials that are not given are asked to the user
+// Without a terminal the flags for the missing credentials are required
+func (o *options) credentials(c network.Credentials, pi network.ProviderInfo) (string, string, error) {
+	if o.username != "" && c.Username == "" {
+		if !strings.HasPrefix(o.username, c.Prefix) {
+			return "", "", fmt.Errorf("the username does not begin with: '%s'", c.Prefix)
+		}
+		if !strings.HasSuffix(o.username, c.Suffix) {
+			return "", "", fmt.Errorf("the username does not end with: '%s'", c.Suffix)
+		}
+		c.Username = o.username
+	}
+	if o.passwordFile != "" && c.Password == "" {
+		pwd, err := readSecret(o.passwordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the password file: %w", err)
+		}
+		if pwd == "" {
+			return "", "", fmt.Errorf("the password file %s is empty", o.passwordFile)
+		}
+		c.Password = pwd
+	}
+	if !IsTerminal() {
+		if c.Username == "" {
+			return "", "", errMissing("username", "the credentials")
+		}
+		// a one-time token is asked by NetworkManager when connecting
+		if c.Password == "" && !c.OneTimeToken {
+			return "", "", errMissing("password-file", "the credentials")
+		}
+		return c.Username, c.Password, nil
+	}
+	return askCredentials(c, pi)
+}
+
+// certificate fills in the client certificate and passphrase from the PKCS12 and passphrase file flags
+// The ones that are not given are asked to the user
+// Without a terminal the flags are required, unless the EAP metadata contains the certificate or passphrase
+func (o *options) certificate(cert string, pass string, pi network.ProviderInfo) (string, string, error) {
+	if o.pkcs12 != "" && cert == "" {
+		b, err := os.ReadFile(o.pkcs12)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the PKCS12 file: %w", err)
+		}
+		cert = string(b)
+	}
+	if o.passphraseFile != "" {
+		// an empty passphrase is valid here
+		var err error
+		pass, err = readSecret(o.passphraseFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read the passphrase file: %w", err)
+		}
+		if cert != "" {
+			return cert, pass, nil
+		}
+	}
+	if !IsTerminal() {
+		if cert == "" {
+			return "", "", errMissing("pkcs12", "the client certificate")
+		}
+		// an empty file can be given for a certificate without a passphrase
+		if pass == "" {
+			return "", "", errMissing("passphrase-file", "the passphrase of the client certificate")
+		}
+		return cert, pass, nil
+	}
+	return askCertificate(cert, pass, pi)
+}
+
+// media gets the medium using the medium flag
+// If the flag is not given, the user is asked to choose one
+// Without a terminal both are installed
+func (o *options) media(pi network.ProviderInfo) (network.Medium, error) {
+	if o.medium != "" {
+		return network.ParseMedium(o.medium)
+	}
+	if !IsTerminal() {
+		return network.MediumBoth, nil
+	}
+	return askMedium(pi), nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"cmp"
+	"sync"
+
+	"github.com/jwijenbergh/puregotk/v4/adw"
+	"github.com/jwijenbergh/puregotk/v4/gtk"
+)
+
+// ChoiceState is the state for letting the user choose one of multiple options
+// e.g. one of the identity providers in an EAP config
+// It reuses the page for choosing a profile
+type ChoiceState struct {
+	builder *gtk.Builder
+	stack   *adw.ViewStack
+	title   string
+	labels  []string
+	// sorter sorts the options, if nil the order of the labels is kept
+	sorter func(a, b int) int
+	sl     *SelectList
+	wg     sync.WaitGroup
+	// once makes sure only the first activation is chosen, e.g. on a double click
+	once   sync.Once
+	chosen int
+}
+
+func NewChoiceState(builder *gtk.Builder, stack *adw.ViewStack, title string, labels []string, sorter func(a, b int) int) *ChoiceState {
+	if sorter == nil {
+		sorter = cmp.Compare[int]
+	}
+	return &ChoiceState{
+		builder: builder,
+		stack:   stack,
+		title:   title,
+		labels:  labels,
+		sorter:  sorter,
+	}
+}
+
+func (s *ChoiceState) Initialize() {
+	s.wg.Add(1)
+	uiThread(func() {
+		var page adw.ViewStackPage
+		s.builder.GetObject("profilePage").Cast(&page)
+		defer page.Unref()
+		var scroll gtk.ScrolledWindow
+		s.builder.GetObject("profileScroll").Cast(&scroll)
+		defer scroll.Unref()
+		var list gtk.ListView
+		s.builder.GetObject("profileList").Cast(&list)
+		defer list.Unref()
+
+		var label gtk.Label
+		s.builder.GetObject("profileLabel").Cast(&label)
+		defer label.Unref()
+		styleWidget(&label, "label")
+		label.SetText(s.title)
+
+		activated := func(idx int) {
+			s.once.Do(func() {
+				s.chosen = idx
+				uiThread(func() {
+					s.sl.Destroy()
+				})
+				s.wg.Done()
+			})
+		}
+		s.sl = NewSelectList(&scroll, &list, activated, s.sorter)
+		for idx, l := range s.labels {
+			s.sl.Add(idx, l)
+		}
+		s.sl.Setup()
+		setPage(s.stack, &page)
+	})
+}
+
+// Get waits for the user to choose an option and returns the index of the label
+func (s *ChoiceState) Get() int {
+	s.wg.Wait()
+	return s.chosen
+}
@@ -29,13 +29,15 @@ type CredentialsState struct {
 	builder *gtk.Builder
 	cred    network.Credentials
 
-	user gtk.Entry
-	pwd  gtk.PasswordEntry
+	user     gtk.Entry
+	pwd      gtk.PasswordEntry
+	pwdLabel gtk.Label
 }
 
 func (l *CredentialsState) Destroy() {
 	l.user.Unref()
 	l.pwd.Unref()
+	l.pwdLabel.Unref()
 }
 
 func (l *CredentialsState) Prefix() string {
@@ -57,7 +59,8 @@ func (l *CredentialsState) Validate() error {
 	if !strings.HasSuffix(ut, l.cred.Suffix) {
 		return fmt.Errorf("username must end with: \"%s\"", l.cred.Suffix)
 	}
-	if l.pwd.GetText() == "" {
+	// a one-time token is asked by NetworkManager when connecting
+	if l.pwd.GetText() == "" && !l.cred.OneTimeToken {
 		return errors.New("password cannot be empty")
 	}
 	return nil
@@ -71,4 +74,10 @@ func (l *CredentialsState) Initialize() {
 
 	l.builder.GetObject("loginPasswordText").Cast(&l.pwd)
 	l.pwd.SetText(l.cred.Password)
+
+	// EAP-GTC uses a one-time token instead of a password
+	// The token is not saved, NetworkManager asks for a new one every time you connect
+	l.builder.GetObject("loginPasswordLabel").Cast(&l.pwdLabel)
+	l.pwdLabel.SetVisible(!l.cred.OneTimeToken)
+	l.pwd.SetVisible(!l.cred.OneTimeToken)
 }
@@ -65,6 +65,21 @@ func (s *SelectList) Destroy() {
 
 func (s *SelectList) Add(idx int, label string) {
 	s.store.Append(label)
+	s.setIndex(idx)
+}
+
+// SetLabel replaces the label of the item with the index and sorts and filters the list again
+// The items are added in order of their index, so the index is also the position in the store
+// This can only be used after Setup
+func (s *SelectList) SetLabel(idx int, label string) {
+	s.store.Splice(uint(idx), 1, []string{label})
+	// the new item is sorted before it has the model index, so sort again
+	s.setIndex(idx)
+	s.Changed()
+}
+
+// setIndex stores the model index in the item at the position of the index
+func (s *SelectList) setIndex(idx int) {
 	var strobj gtk.StringObject
 	// TODO: this is quite hacky but puregotk doesn't support subclassing yet
 	// We have to store the mondel index as the position will not always match 1:1
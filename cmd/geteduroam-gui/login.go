@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/base64"
 	"fmt"
 	"sync"
 
@@ -83,18 +82,16 @@ func (l *LoginBase) Submit() {
 }
 
 func (l *LoginBase) fillLogo(logo *gtk.Image) error {
-	d, err := base64.StdEncoding.DecodeString(l.pi.Logo)
+	// PNG, JPEG and SVG logos are all loaded by gdk-pixbuf
+	pb, err := bytesPixbufAtScale(l.pi.Logo.Data, 100, 100)
 	if err != nil {
-		return err
-	}
-	pb, err := bytesPixbuf(d)
-	if err == nil {
-		uiThread(func() {
-			defer logo.Unref()
-			logo.SetFromPixbuf(pb)
-			logo.SetSizeRequest(100, 100)
-		})
+		return fmt.Errorf("failed to load the %s logo: %w", l.pi.Logo.Mime, err)
 	}
+	uiThread(func() {
+		defer logo.Unref()
+		logo.SetFromPixbuf(pb)
+		logo.SetSizeRequest(100, 100)
+	})
 	return nil
 }
 
@@ -123,11 +120,11 @@ func (l *LoginBase) Initialize() {
 	l.GetObject("InstanceLogo", &logo)
 	defer logo.Unref()
 
-	if l.pi.Logo != "" {
+	if len(l.pi.Logo.Data) > 0 {
 		err := l.fillLogo(&logo)
-		// TODO: do not panic here but just log
 		if err != nil {
-			panic(err)
+			slog.Error("failed to fill the logo", "error", err)
+			logo.Hide()
 		}
 	} else {
 		logo.Hide()
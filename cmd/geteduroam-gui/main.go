@@ -2,12 +2,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +25,7 @@ import (
 	"github.com/geteduroam/linux-app/internal/clientver"
 	"github.com/geteduroam/linux-app/internal/discovery"
 	"github.com/geteduroam/linux-app/internal/handler"
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/logwrap"
 	"github.com/geteduroam/linux-app/internal/network"
 	"github.com/geteduroam/linux-app/internal/provider"
@@ -35,12 +38,15 @@ type serverList struct {
 	providers provider.Providers
 	list      *SelectList
 	custom    bool
+	// scores are the search scores of the providers for scoresQuery
+	// These are cached as scoring is too expensive to do for every comparison when sorting
+	scores      map[int]int
+	scoresQuery string
 }
 
-func (s *serverList) get(idx int, query string) (*provider.Provider, error) {
+func (s *serverList) get(ctx context.Context, idx int, query string) (*provider.Provider, error) {
 	if s.custom && idx == len(s.providers) {
-		// TODO: add context
-		return provider.Custom(context.Background(), query)
+		return provider.Custom(ctx, query)
 	}
 	if idx < 0 || idx > len(s.providers) {
 		return nil, errors.New("index out of range")
@@ -48,14 +54,34 @@ func (s *serverList) get(idx int, query string) (*provider.Provider, error) {
 	return &s.providers[idx], nil
 }
 
-func (s *serverList) getNames(idx int, query string) (*provider.LocalizedStrings, error) {
+// getSortable gets the provider that is used for sorting and filtering
+// For the custom entry only the name is filled in
+func (s *serverList) getSortable(idx int, query string) (*provider.Provider, error) {
 	if s.custom && idx == len(s.providers) {
-		return &provider.LocalizedStrings{{Display: query}}, nil
+		return &provider.Provider{Name: provider.LocalizedStrings{{Display: query}}}, nil
 	}
 	if idx < 0 || idx > len(s.providers) {
 		return nil, errors.New("index out of range")
 	}
-	return &s.providers[idx].Name, nil
+	return &s.providers[idx], nil
+}
+
+// score gets the search score of the provider at the index, see provider.Provider.Match
+func (s *serverList) score(idx int, query string) (int, error) {
+	if s.scores == nil || s.scoresQuery != query {
+		s.scores = make(map[int]int)
+		s.scoresQuery = query
+	}
+	if score, ok := s.scores[idx]; ok {
+		return score, nil
+	}
+	p, err := s.getSortable(idx, query)
+	if err != nil {
+		return 0, err
+	}
+	score := p.Match(query).Score
+	s.scores[idx] = score
+	return score, nil
 }
 
 func (s *serverList) Fill() {
@@ -82,12 +108,25 @@ func (s *serverList) RemoveCustom() {
 	s.custom = false
 }
 
+// position is the position of the user that is used to sort the profiles by distance
+// It is nil if the position is not known
+var position *network.Location
+
+// authMethod is the name of the authentication method to use when a profile offers multiple, e.g. ttls-pap or peap
+// If it is empty the user is asked
+var authMethod string
+
+// pmf is the protected management frames policy that overrides the policy derived from the EAP config
+var pmf network.PMF
+
 type mainState struct {
 	app     *adw.Application
 	builder *gtk.Builder
 	servers *serverList
 	scroll  *gtk.ScrolledWindow
 	stack   *adw.ViewStack
+	// wired are the IDs of the wired connections that were added by the last configuration
+	wired []string
 }
 
 func (m *mainState) initServers() {
@@ -118,21 +157,82 @@ func (m *mainState) askCertificate(cert string, pwd string, pi network.ProviderI
 	return cert, pass, nil
 }
 
+func (m *mainState) askIdentityProvider(candidates []network.Network) (int, error) {
+	// the distance to the candidate, a negative distance is unknown
+	dists := make([]float64, len(candidates))
+	labels := make([]string, len(candidates))
+	for idx, c := range candidates {
+		pi := c.ProviderInfo()
+		labels[idx] = pi.Name
+		if pi.Description != "" {
+			labels[idx] += " - " + pi.Description
+		}
+		dists[idx] = -1
+		if position == nil {
+			continue
+		}
+		if _, d, ok := pi.Nearest(*position); ok {
+			dists[idx] = d
+			labels[idx] = fmt.Sprintf("%s (%.0f km)", labels[idx], d)
+		}
+	}
+	sorter := func(a, b int) int {
+		// the nearest first, the ones with an unknown distance keep the order of the EAP config
+		if dists[a] >= 0 && dists[b] >= 0 {
+			if c := cmp.Compare(dists[a], dists[b]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a, b)
+	}
+	s := NewChoiceState(m.builder, m.stack, "Please select an organization: ", labels, sorter)
+	s.Initialize()
+	return s.Get(), nil
+}
+
+func (m *mainState) askMedium(_ network.ProviderInfo) (network.Medium, error) {
+	media := []network.Medium{network.MediumBoth, network.MediumWireless, network.MediumWired}
+	labels := []string{"Wireless and wired", "Wireless only", "Wired only"}
+	s := NewChoiceState(m.builder, m.stack, "This profile can be used for wireless and wired networks, please select where to add it: ", labels, nil)
+	s.Initialize()
+	return media[s.Get()], nil
+}
+
+func (m *mainState) askMethod(candidates []network.Network) (int, error) {
+	labels := make([]string, len(candidates))
+	for idx, c := range candidates {
+		labels[idx] = strings.ToUpper(c.MethodName())
+	}
+	// the candidates are in order of preference
+	labels[0] += " (preferred)"
+	s := NewChoiceState(m.builder, m.stack, "This profile offers multiple authentication methods, please select one: ", labels, nil)
+	s.Initialize()
+	return s.Get(), nil
+}
+
 func (m *mainState) file(metadata []byte) (*time.Time, *time.Time, error) {
+	m.wired = nil
 	h := handler.Handlers{
 		CredentialsH: m.askCredentials,
 		CertificateH: m.askCertificate,
+		ProviderH:    m.askIdentityProvider,
+		MediumH:      m.askMedium,
+		MethodH:      m.askMethod,
+		Method:       authMethod,
+		PMF:          pmf,
+		WiredH: func(ids []string) {
+			m.wired = ids
+		},
 	}
 	return h.Configure(metadata)
 }
 
-func (m *mainState) direct(p provider.Profile) error {
-	config, err := p.EAPDirect()
+func (m *mainState) direct(ctx context.Context, p provider.Profile) (*time.Time, *time.Time, error) {
+	config, err := p.EAPDirect(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	_, _, err = m.file(config)
-	return err
+	return m.file(config)
 }
 
 func (m *mainState) local(path string) (*time.Time, *time.Time, error) {
@@ -172,9 +272,11 @@ func (m *mainState) rowActivated(sel provider.Provider) {
 	var page gtk.Box
 	m.builder.GetObject("searchPage").Cast(&page)
 	defer page.Unref()
-	l := NewLoadingPage(m.builder, m.stack, "Loading organization details...", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	l := NewLoadingPage(m.builder, m.stack, "Loading organization details...", func() {
+		cancel()
+	})
 	l.Initialize()
-	ctx := context.Background()
 	chosen := func(p provider.Profile) (err error) {
 		defer func() {
 			err = ensureContextError(ctx, err)
@@ -184,7 +286,7 @@ func (m *mainState) rowActivated(sel provider.Provider) {
 		var isredirect bool
 		switch p.Flow() {
 		case provider.DirectFlow:
-			err = m.direct(p)
+			vBeg, vEnd, err = m.direct(ctx, p)
 			if err != nil {
 				return err
 			}
@@ -205,13 +307,14 @@ func (m *mainState) rowActivated(sel provider.Provider) {
 			}
 			fmt.Println("Browser has been opened with URL:", url)
 		}
-		s := NewSuccessState(m.builder, m.app.GetActiveWindow(), m.stack, vBeg, vEnd, isredirect)
+		s := NewSuccessState(m.builder, m.app.GetActiveWindow(), m.stack, vBeg, vEnd, isredirect, m.wired)
 		uiThread(func() {
 			s.Initialize()
 		})
 		return nil
 	}
 	cb := func(p provider.Profile) {
+		defer cancel()
 		err := chosen(p)
 		if err != nil {
 			l.Hide()
@@ -220,35 +323,72 @@ func (m *mainState) rowActivated(sel provider.Provider) {
 		}
 	}
 	if len(sel.Profiles) > 1 {
-		profiles := NewProfileState(m.builder, m.stack, sel.Profiles, cb)
+		// the eap-configs are cached in the profiles, do not modify the ones from the list
+		profs := slices.Clone(sel.Profiles)
+		// the profiles are shown right away sorted by the cached distances
+		// the other distances are downloaded in the background until a profile is chosen
+		dctx, dcancel := context.WithCancel(ctx)
+		profiles := NewProfileState(m.builder, m.stack, profs, cachedDistances(profs, position), func(p provider.Profile) {
+			dcancel()
+			cb(p)
+		})
 		profiles.Initialize()
+		profileDistances(dctx, profs, position, profiles.SetDistance)
 	} else {
 		go cb(sel.Profiles[0])
 	}
 }
 
+// initList loads the organizations from discovery without blocking the UI
+// The loading can be cancelled, the search page is then shown with the previously cached list, if any
 func (m *mainState) initList() {
+	ctx, cancel := context.WithCancel(context.Background())
+	l := NewLoadingPage(m.builder, m.stack, "Loading organizations...", func() {
+		cancel()
+	})
+	l.Initialize()
+	go func() {
+		defer cancel()
+		inst, err := discovery.NewCache().Providers(ctx)
+		uiThread(func() {
+			l.Hide()
+			m.activate()
+			if err != nil {
+				if len(*inst) == 0 && ctx.Err() != nil {
+					err = errors.New("loading the organizations was cancelled, please restart to try again")
+				}
+				m.ShowError(err)
+				// we only continue if there is still a previously cached list
+				if len(*inst) == 0 {
+					return
+				}
+			}
+			m.servers.providers = *inst
+			m.setupList()
+		})
+	}()
+}
+
+// setupList sets up the search list with the organizations from discovery
+func (m *mainState) setupList() {
 	// style the treeview
 	var list gtk.ListView
 	m.builder.GetObject("searchList").Cast(&list)
 	defer list.Unref()
 
-	cache := discovery.NewCache()
-	inst, err := cache.Providers()
-	if err != nil {
-		m.ShowError(err)
-		return
-	}
-	m.servers.providers = *inst
-
 	var search gtk.SearchEntry
 	m.builder.GetObject("searchBox").Cast(&search)
 	defer search.Unref()
 
 	activated := func(idx int) {
-		l := NewLoadingPage(m.builder, m.stack, "Loading server details...", nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		l := NewLoadingPage(m.builder, m.stack, "Loading server details...", func() {
+			cancel()
+		})
 		l.Initialize()
 		cb := func(inst *provider.Provider, err error) {
+			defer cancel()
+			err = ensureContextError(ctx, err)
 			if err != nil {
 				l.Hide()
 				m.activate()
@@ -258,7 +398,7 @@ func (m *mainState) initList() {
 			m.rowActivated(*inst)
 		}
 		go func() {
-			inst, err := m.servers.get(idx, search.GetText())
+			inst, err := m.servers.get(ctx, idx, search.GetText())
 			uiThread(func() {
 				cb(inst, err)
 			})
@@ -267,24 +407,32 @@ func (m *mainState) initList() {
 
 	sorter := func(a, b int) int {
 		query := search.GetText()
-		n1, err := m.servers.getNames(a, query)
+		p1, err := m.servers.getSortable(a, query)
+		if err != nil {
+			return -1
+		}
+		p2, err := m.servers.getSortable(b, query)
+		if err != nil {
+			return -1
+		}
+		s1, err := m.servers.score(a, query)
 		if err != nil {
 			return -1
 		}
-		n2, err := m.servers.getNames(b, query)
+		s2, err := m.servers.score(b, query)
 		if err != nil {
 			return -1
 		}
-		return provider.SortNames(*n1, *n2, query)
+		return provider.SortScored(*p1, s1, *p2, s2)
 	}
 
 	m.servers.list = NewSelectList(m.scroll, &list, activated, sorter).WithFiltering(func(idx int) bool {
 		query := search.GetText()
-		n, err := m.servers.getNames(idx, query)
+		score, err := m.servers.score(idx, query)
 		if err != nil {
 			return false
 		}
-		return provider.FilterSingle(*n, query)
+		return score > 0
 	})
 
 	// Fill the servers in the select list
@@ -331,7 +479,7 @@ func (m *mainState) localMetadata() {
 				})
 				return
 			}
-			s := NewSuccessState(m.builder, m.app.GetActiveWindow(), m.stack, vBeg, vEnd, false)
+			s := NewSuccessState(m.builder, m.app.GetActiveWindow(), m.stack, vBeg, vEnd, false, m.wired)
 			s.Initialize()
 		}()
 	})
@@ -393,9 +541,9 @@ func (m *mainState) Initialize() {
 	m.stack = &adw.ViewStack{}
 	m.builder.GetObject("pageStack").Cast(m.stack)
 	m.initServers()
-	m.initList()
 	m.initBurger()
-	m.activate()
+	// the search page is activated once the organizations are loaded
+	m.initList()
 }
 
 func (m *mainState) ShowError(err error) {
@@ -466,6 +614,11 @@ func main() {
   -h, --help			Prints this help information
   --version			Prints version information
   -d, --debug			Debug
+  --country=<code>		The country code, e.g. NL, of which organizations are shown first (default: from the region of the language)
+  --lang=<languages>		The languages, e.g. nl_NL or nl,en, in which names and descriptions are shown (default: from the "language" config key or $LC_ALL, $LC_MESSAGES, $LANG and $LANGUAGE)
+  --location=<lat,lon>		Your position, e.g. 52.0,4.36, to sort the profiles of an organization by distance (default: from the "location" config key)
+  --method=<method>		The authentication method to use when a profile offers multiple, e.g. ttls-pap or peap (default: ask)
+  --pmf=<policy>		The protected management frames policy for Wi-Fi networks: default, disable, optional or required (default: from the "pmf" config key, otherwise derived from the profile)
   --gtk-args                    Arguments to pass to gtk as a string, e.g. "--help". These flags are split on spaces
 
   This GUI binary is used to add an eduroam connection profile with integration using NetworkManager and Gtk.
@@ -477,6 +630,10 @@ func main() {
 	var versionf bool
 	var debug bool
 	var gtkarg string
+	var country string
+	var langf string
+	var location string
+	var pmff string
 	program := fmt.Sprintf("%s-gui", variant.DisplayName)
 	lpath, err := logwrap.Location(program)
 	if err != nil {
@@ -488,6 +645,11 @@ func main() {
 	flag.BoolVar(&debug, "d", false, "Debug")
 	flag.BoolVar(&debug, "debug", false, "Debug")
 	flag.StringVar(&gtkarg, "gtk-args", "", "Gtk arguments")
+	flag.StringVar(&country, "country", "", "The country of which organizations are shown first")
+	flag.StringVar(&langf, "lang", "", "The languages in which names and descriptions are shown")
+	flag.StringVar(&location, "location", "", "Your position as latitude,longitude")
+	flag.StringVar(&authMethod, "method", "", "The authentication method to use when a profile offers multiple, e.g. ttls-pap or peap")
+	flag.StringVar(&pmff, "pmf", "", "The protected management frames policy: default, disable, optional or required")
 	flag.Usage = func() { fmt.Printf(usage, program, lpath) }
 	flag.Parse()
 	if help {
@@ -500,6 +662,28 @@ func main() {
 		return
 	}
 
+	if country != "" {
+		if err := provider.SetCountry(country); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid --country flag: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	position, err = handler.Position(location)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --location flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	pmf, err = handler.PMFPolicy(pmff)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --pmf flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	var handler glib.LogFunc = func(pkg string, level glib.LogLevelFlags, msg string, _ uintptr) {
 		switch level {
 		case glib.GLogLevelErrorValue:
@@ -529,6 +713,11 @@ func main() {
 	glib.LogSetDefaultHandler(&handler, 0)
 
 	logwrap.Initialize(program, debug)
+	if err := lang.Configure(langf); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --lang flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
 	ui := ui{}
 	args := []string{os.Args[0]}
 	if gtkarg != "" {
@@ -1,28 +1,165 @@
 package main
 
 import (
+	"cmp"
+	"context"
+	"fmt"
+	"sync"
+
 	"golang.org/x/exp/slog"
 
+	"github.com/geteduroam/linux-app/internal/eap"
+	"github.com/geteduroam/linux-app/internal/network"
 	"github.com/geteduroam/linux-app/internal/provider"
 	"github.com/jwijenbergh/puregotk/v4/adw"
 	"github.com/jwijenbergh/puregotk/v4/gtk"
 )
 
+// maxDistanceDownloads is the maximum number of eap-configs that are downloaded at once to get the profile distances
+const maxDistanceDownloads = 4
+
+// distanceCache caches the distances of the profiles by their eap-config endpoint
+// This is such that the eap-configs are not downloaded again when an organization is chosen again
+// The position of the user does not change while the GUI runs
+var distanceCache = struct {
+	sync.Mutex
+	dists map[string]float64
+}{dists: make(map[string]float64)}
+
+// profileDistance gets the distance in kilometers from the position to the nearest location of the profile
+// The eap-config is cached in the profile such that it is not downloaded again when the profile is chosen
+// It returns a negative distance if it is not known
+func profileDistance(ctx context.Context, p *provider.Profile, from network.Location) float64 {
+	b, err := p.EAPDirect(ctx)
+	if err != nil {
+		slog.Debug("failed to get the eap-config for the profile distance", "profile", p.ID, "error", err)
+		return -1
+	}
+	p.CachedResponse = b
+	list, err := eap.Parse(b)
+	if err != nil {
+		slog.Debug("failed to parse the eap-config for the profile distance", "profile", p.ID, "error", err)
+		return -1
+	}
+	dist := -1.0
+	// the nearest location of all identity providers in the eap-config
+	for _, idp := range list.EAPIdentityProviders {
+		if idp == nil {
+			continue
+		}
+		if _, d, ok := idp.PInfo().Nearest(from); ok && (dist < 0 || d < dist) {
+			dist = d
+		}
+	}
+	return dist
+}
+
+// cachedDistances gets the cached distances of the profiles, see distanceCache
+// A distance is negative if it is not known yet
+func cachedDistances(profiles []provider.Profile, from *network.Location) []float64 {
+	dists := make([]float64, len(profiles))
+	for i := range dists {
+		dists[i] = -1
+	}
+	if from == nil {
+		return dists
+	}
+	distanceCache.Lock()
+	defer distanceCache.Unlock()
+	for i, p := range profiles {
+		if d, ok := distanceCache.dists[p.EapConfigEndpoint]; ok && p.Flow() == provider.DirectFlow {
+			dists[i] = d
+		}
+	}
+	return dists
+}
+
+// profileDistances gets the distances in kilometers from the position to the nearest location of the profiles that are not cached yet
+// The locations are only known for profiles that directly give an eap-config
+// These are downloaded with at most maxDistanceDownloads at once and the distances are cached, see distanceCache
+// The found callback is called on the UI thread for each distance with the downloaded eap-config, it is not called once the context is cancelled
+func profileDistances(ctx context.Context, profiles []provider.Profile, from *network.Location, found func(idx int, d float64, eapconfig []byte)) {
+	if from == nil {
+		return
+	}
+	sem := make(chan struct{}, maxDistanceDownloads)
+	for i, p := range profiles {
+		if p.Flow() != provider.DirectFlow {
+			continue
+		}
+		distanceCache.Lock()
+		_, ok := distanceCache.dists[p.EapConfigEndpoint]
+		distanceCache.Unlock()
+		if ok {
+			continue
+		}
+		// p is a copy, the profiles are only modified on the UI thread
+		go func() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+			d := profileDistance(ctx, &p, *from)
+			// do not cache the distance if the loading was cancelled, it is unknown then
+			if ctx.Err() != nil {
+				return
+			}
+			distanceCache.Lock()
+			distanceCache.dists[p.EapConfigEndpoint] = d
+			distanceCache.Unlock()
+			uiThread(func() {
+				if ctx.Err() != nil {
+					return
+				}
+				found(i, d, p.CachedResponse)
+			})
+		}()
+	}
+}
+
 type ProfileState struct {
 	builder  *gtk.Builder
 	stack    *adw.ViewStack
 	profiles []provider.Profile
-	success  func(provider.Profile)
-	sl       *SelectList
+	// distances are the distances in kilometers to the profiles, a negative distance is unknown
+	distances []float64
+	success   func(provider.Profile)
+	sl        *SelectList
 }
 
-func NewProfileState(builder *gtk.Builder, stack *adw.ViewStack, profiles []provider.Profile, success func(provider.Profile)) *ProfileState {
+func NewProfileState(builder *gtk.Builder, stack *adw.ViewStack, profiles []provider.Profile, distances []float64, success func(provider.Profile)) *ProfileState {
 	return &ProfileState{
-		builder:  builder,
-		stack:    stack,
-		profiles: profiles,
-		success:  success,
+		builder:   builder,
+		stack:     stack,
+		profiles:  profiles,
+		distances: distances,
+		success:   success,
+	}
+}
+
+// label returns the label of a profile with the distance if it is known
+func (p *ProfileState) label(idx int) string {
+	label := p.profiles[idx].Name.Get()
+	if d := p.distances[idx]; d >= 0 {
+		label = fmt.Sprintf("%s (%.0f km)", label, d)
+	}
+	return label
+}
+
+// SetDistance sets the distance of a profile that was found after the profiles were shown, see profileDistances
+// The downloaded eap-config is cached in the profile such that it is not downloaded again when the profile is chosen
+func (p *ProfileState) SetDistance(idx int, d float64, eapconfig []byte) {
+	if p.profiles[idx].CachedResponse == nil {
+		p.profiles[idx].CachedResponse = eapconfig
+	}
+	if d < 0 {
+		return
 	}
+	p.distances[idx] = d
+	// replacing the label sorts the profile again
+	p.sl.SetLabel(idx, p.label(idx))
 }
 
 func (p *ProfileState) Destroy() {
@@ -52,14 +189,29 @@ func (p *ProfileState) Initialize() {
 	p.builder.GetObject("profileLabel").Cast(&label)
 	defer label.Unref()
 	styleWidget(&label, "label")
+	// the page is shared with choosing an identity provider
+	label.SetText("Please select a profile: ")
 
 	sorter := func(a, b int) int {
+		// the nearest profiles first, the ones with an unknown distance last
+		da, db := p.distances[a], p.distances[b]
+		if (da < 0) != (db < 0) {
+			if da < 0 {
+				return 1
+			}
+			return -1
+		}
+		if c := cmp.Compare(da, db); c != 0 {
+			return c
+		}
 		// Here we have no search query
 		return provider.SortNames(p.profiles[a].Name, p.profiles[b].Name, "")
 	}
 	activated := func(idx int) {
+		// copy the profile here as the profiles are modified on the UI thread when a distance is found
+		prof := p.profiles[idx]
 		go func() {
-			p.success(p.profiles[idx])
+			p.success(prof)
 			uiThread(func() {
 				p.Destroy()
 			})
@@ -68,8 +220,8 @@ func (p *ProfileState) Initialize() {
 
 	p.sl = NewSelectList(&scroll, &list, activated, sorter)
 
-	for idx, prof := range p.profiles {
-		p.sl.Add(idx, prof.Name.Get())
+	for idx := range p.profiles {
+		p.sl.Add(idx, p.label(idx))
 	}
 
 	p.sl.Setup()
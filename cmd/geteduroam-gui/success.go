@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/geteduroam/linux-app/internal/notification"
@@ -19,9 +20,11 @@ type SuccessState struct {
 	vBeg       *time.Time
 	vEnd       *time.Time
 	isredirect bool
+	// wired are the IDs of the added wired connections, these are not activated automatically
+	wired []string
 }
 
-func NewSuccessState(builder *gtk.Builder, parent *gtk.Window, stack *adw.ViewStack, vBeg *time.Time, vEnd *time.Time, isredirect bool) *SuccessState {
+func NewSuccessState(builder *gtk.Builder, parent *gtk.Window, stack *adw.ViewStack, vBeg *time.Time, vEnd *time.Time, isredirect bool, wired []string) *SuccessState {
 	return &SuccessState{
 		builder:    builder,
 		parent:     parent,
@@ -29,6 +32,7 @@ func NewSuccessState(builder *gtk.Builder, parent *gtk.Window, stack *adw.ViewSt
 		vBeg:       vBeg,
 		vEnd:       vEnd,
 		isredirect: isredirect,
+		wired:      wired,
 	}
 }
 
@@ -59,7 +63,11 @@ func (s *SuccessState) Initialize() {
 	s.builder.GetObject("successSubTitle").Cast(&sub)
 	defer sub.Unref()
 	sub.SetVisible(!s.isredirect)
-	sub.SetText(fmt.Sprintf("Your %s profile has been added", variant.ProfileName))
+	subText := fmt.Sprintf("Your %s profile has been added", variant.ProfileName)
+	if len(s.wired) > 0 {
+		subText += fmt.Sprintf("\n\nThe wired connections are not activated automatically. When you are connected to a wired network of your organization, activate %s in the network settings", strings.Join(s.wired, ", "))
+	}
+	sub.SetText(subText)
 	styleWidget(&sub, "label")
 
 	var valid gtk.Label
@@ -45,7 +45,8 @@ func showErrorToast(overlay adw.ToastOverlay, err error) {
 	overlay.AddToast(toast)
 }
 
-func bytesPixbuf(b []byte) (*gdkpixbuf.Pixbuf, error) {
+// tempPixbuf writes the image to a temporary file and loads it as a pixbuf using the load function
+func tempPixbuf(b []byte, load func(path string) (*gdkpixbuf.Pixbuf, error)) (*gdkpixbuf.Pixbuf, error) {
 	// TODO: do this without creating a temp file
 	f, err := os.CreateTemp("/tmp", fmt.Sprintf("%s-pixbuf", variant.DisplayName))
 	if err != nil {
@@ -56,13 +57,25 @@ func bytesPixbuf(b []byte) (*gdkpixbuf.Pixbuf, error) {
 	if err != nil {
 		return nil, err
 	}
-	pb, err := gdkpixbuf.NewPixbufFromFile(f.Name())
+	pb, err := load(f.Name())
 	if err != nil {
 		return nil, err
 	}
 	return pb, nil
 }
 
+func bytesPixbuf(b []byte) (*gdkpixbuf.Pixbuf, error) {
+	return tempPixbuf(b, gdkpixbuf.NewPixbufFromFile)
+}
+
+// bytesPixbufAtScale loads the image scaled to fit the width and height, keeping the aspect ratio
+// This also renders vector images, e.g. SVG, at the right size
+func bytesPixbufAtScale(b []byte, width int, height int) (*gdkpixbuf.Pixbuf, error) {
+	return tempPixbuf(b, func(path string) (*gdkpixbuf.Pixbuf, error) {
+		return gdkpixbuf.NewPixbufFromFileAtScale(path, width, height, true)
+	})
+}
+
 func uiThread(cb func()) {
 	var idlecb glib.SourceFunc
 	idlecb = func(uintptr) bool {
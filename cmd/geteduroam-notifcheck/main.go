@@ -4,11 +4,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
 	"time"
 
 	"golang.org/x/exp/slog"
 
 	"github.com/geteduroam/linux-app/internal/config"
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/logwrap"
 	"github.com/geteduroam/linux-app/internal/nm"
 	"github.com/geteduroam/linux-app/internal/notification"
@@ -17,6 +19,7 @@ import (
 
 const usage = `Usage of %s:
   -h, --help			Prints this help information
+  --lang=<languages>		The languages, e.g. nl_NL or nl,en, of the notifications (default: from the "language" config key or $LC_ALL, $LC_MESSAGES, $LANG and $LANGUAGE)
 
   This CLI binary is needed for periodically checking for validity and giving notifications when the eduroam connection profile added by %s is about to expire.
   It gives a warning 10 days before expiry, and then every day. You can schedule to start this binary daily yourself or rely on the built-in systemd user timer.
@@ -47,9 +50,16 @@ func main() {
 	if err != nil {
 		lpath = "N/A"
 	}
+	var langf string
+	flag.StringVar(&langf, "lang", "", "The languages of the notifications")
 	flag.Usage = func() { fmt.Printf(usage, program, variant.DisplayName, lpath) }
 	flag.Parse()
 	logwrap.Initialize(fmt.Sprintf("%s-notifcheck", variant.DisplayName), false)
+	if err := lang.Configure(langf); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --lang flag: %v\n", err)
+		flag.Usage()
+		os.Exit(1)
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		slog.Error("no previous state", "error", err)
@@ -70,22 +80,11 @@ func main() {
 	diff := valid.Sub(now)
 	days := int(diff.Hours() / 24)
 
-	var text string
 	if days > 10 {
 		slog.Info("the profile is still valid for more than 10 days", "days", days)
 		return
 	}
-	if days < 0 {
-		text = "profile is expired"
-	}
-	if days == 0 {
-		text = "profile expires today"
-	}
-	if days > 0 {
-		text = fmt.Sprintf("profile expires in %d days", days)
-	}
-	msg := fmt.Sprintf("Your eduroam %s. Re-run %s to renew the profile", text, variant.DisplayName)
-	err = notification.Send(msg)
+	err = notification.Send(expiryMessage(days))
 	if err != nil {
 		slog.Error("failed to send notification", "error", err)
 		return
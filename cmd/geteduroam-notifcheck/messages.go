@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/geteduroam/linux-app/internal/lang"
+	"github.com/geteduroam/linux-app/internal/variant"
+)
+
+// messages are the translations of the expiry notification
+// The first one, English, is used if no translation matches the languages of the user
+var messages = []struct {
+	lang    string
+	expired string
+	today   string
+	// days has the number of days as argument
+	days string
+	// renew has the display name of the app as argument
+	renew string
+}{
+	{
+		lang:    "en",
+		expired: "Your eduroam profile is expired",
+		today:   "Your eduroam profile expires today",
+		days:    "Your eduroam profile expires in %d days",
+		renew:   "Re-run %s to renew the profile",
+	},
+	{
+		lang:    "nl",
+		expired: "Je eduroam-profiel is verlopen",
+		today:   "Je eduroam-profiel verloopt vandaag",
+		days:    "Je eduroam-profiel verloopt over %d dagen",
+		renew:   "Voer %s opnieuw uit om het profiel te vernieuwen",
+	},
+	{
+		lang:    "de",
+		expired: "Dein eduroam-Profil ist abgelaufen",
+		today:   "Dein eduroam-Profil läuft heute ab",
+		days:    "Dein eduroam-Profil läuft in %d Tagen ab",
+		renew:   "Führe %s erneut aus, um das Profil zu erneuern",
+	},
+	{
+		lang:    "fr",
+		expired: "Votre profil eduroam a expiré",
+		today:   "Votre profil eduroam expire aujourd'hui",
+		days:    "Votre profil eduroam expire dans %d jours",
+		renew:   "Relancez %s pour renouveler le profil",
+	},
+}
+
+// expiryMessage returns the notification for a profile that expires in days in the language of the user
+// A negative number of days means the profile is expired
+func expiryMessage(days int) string {
+	tags := make([]string, len(messages))
+	for i, m := range messages {
+		tags[i] = m.lang
+	}
+	idx, ok := lang.Best(tags)
+	if !ok {
+		idx = 0
+	}
+	m := messages[idx]
+	var text string
+	switch {
+	case days < 0:
+		text = m.expired
+	case days == 0:
+		text = m.today
+	default:
+		text = fmt.Sprintf(m.days, days)
+	}
+	return fmt.Sprintf("%s. %s", text, fmt.Sprintf(m.renew, variant.DisplayName))
+}
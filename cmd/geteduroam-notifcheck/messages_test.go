@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/geteduroam/linux-app/internal/lang"
+	"github.com/geteduroam/linux-app/internal/variant"
+)
+
+func TestExpiryMessage(t *testing.T) {
+	defer lang.Set(lang.Preferred()...)
+	cases := []struct {
+		lang language.Tag
+		days int
+		want string
+	}{
+		{lang: language.English, days: -1, want: "Your eduroam profile is expired. Re-run " + variant.DisplayName + " to renew the profile"},
+		{lang: language.English, days: 0, want: "Your eduroam profile expires today. Re-run " + variant.DisplayName + " to renew the profile"},
+		{lang: language.Dutch, days: 3, want: "Je eduroam-profiel verloopt over 3 dagen. Voer " + variant.DisplayName + " opnieuw uit om het profiel te vernieuwen"},
+		{lang: language.MustParse("de-AT"), days: 0, want: "Dein eduroam-Profil läuft heute ab. Führe " + variant.DisplayName + " erneut aus, um das Profil zu erneuern"},
+		// no translation, English is used
+		{lang: language.Japanese, days: 5, want: "Your eduroam profile expires in 5 days. Re-run " + variant.DisplayName + " to renew the profile"},
+	}
+	for _, c := range cases {
+		lang.Set(c.lang)
+		if got := expiryMessage(c.days); got != c.want {
+			t.Fatalf("message for %v not equal, want: %v, got: %v", c.lang, c.want, got)
+		}
+	}
+}
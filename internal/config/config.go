@@ -17,6 +17,14 @@ import (
 type Config struct {
 	UUIDs    []string   `json:"uuids"`
 	Validity *time.Time `json:"validity,omitempty"`
+	// DiscoveryURLs is the ordered list of discovery mirrors that overrides the default discovery URL
+	DiscoveryURLs []string `json:"discovery_urls,omitempty"`
+	// Language is the language, or a comma separated list of languages, that overrides the language from the environment, e.g. nl_NL
+	Language string `json:"language,omitempty"`
+	// Location is the position of the user as "latitude,longitude" that is used to sort by distance, e.g. 52.0,4.36
+	Location string `json:"location,omitempty"`
+	// PMF is the protected management frames policy that overrides the policy derived from the EAP config: default, disable, optional or required
+	PMF string `json:"pmf,omitempty"`
 }
 
 // V1 is the main structure for the old configuration where we only supported one SSID and profile
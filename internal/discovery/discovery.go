@@ -2,14 +2,23 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/exp/slog"
 
+	"github.com/geteduroam/linux-app/internal/config"
+	"github.com/geteduroam/linux-app/internal/httpclient"
 	"github.com/geteduroam/linux-app/internal/provider"
 	"github.com/geteduroam/linux-app/internal/variant"
 )
@@ -27,73 +36,324 @@ type Value struct {
 }
 
 // Cache is the cached discovery list
-// TODO: This should be read from disk so that the app can function when the discovery is offline
+// It is stored on disk in the config directory so that the app can function when the discovery is offline
 type Cache struct {
 	// Cached is the cached list of discovery
 	Cached Discovery `json:"previous"`
 	// LastUpdate is the last time we updated the cache
 	LastUpdate time.Time `json:"updated"`
+	// Expires is the time until the cache is fresh as given by the Cache-Control max-age of the server, capped at maxExpiry
+	// If it is zero, the cache is fresh for an hour after the last update
+	Expires time.Time `json:"expires,omitempty"`
+	// ETag is the ETag validator of the last discovery response
+	ETag string `json:"etag,omitempty"`
+	// LastModified is the Last-Modified validator of the last discovery response
+	LastModified string `json:"last_modified,omitempty"`
+	// Source is the URL of the mirror that the cached list was fetched from
+	// The ETag and Last-Modified validators are only sent to this mirror
+	Source string `json:"source,omitempty"`
+	// Mirrors is the list of discovery mirrors that was configured when the cached list was fetched
+	// If the configured mirrors change, the cached list is only kept as a fallback, see sameMirrors
+	Mirrors []string `json:"mirrors,omitempty"`
+	// URLs is the ordered list of discovery mirrors that are tried when updating
+	URLs []string `json:"-"`
+	// Client is the HTTP client that is used for the requests
+	// If it is nil, the shared httpclient.Client is used
+	Client *http.Client `json:"-"`
+	// noStore is whether the server asked to not store the last response with Cache-Control no-store
+	noStore bool
+}
+
+// cacheName is the filename of the cache in the config directory
+var cacheName = "discovery.json"
+
+// maxExpiry is the longest time the cache is fresh, whatever max-age the server gives
+// This prevents a misconfigured mirror from freezing the list
+const maxExpiry = 24 * time.Hour
+
+// EnvURLs is the environment variable that overrides the discovery URLs, e.g. GETEDUROAM_DISCOVERY_URL
+// Multiple mirrors can be given by separating them with commas
+var EnvURLs = strings.ToUpper(variant.DisplayName) + "_DISCOVERY_URL"
+
+// splitURLs splits a comma separated list of URLs and removes empty entries
+func splitURLs(s string) []string {
+	var urls []string
+	for _, u := range strings.Split(s, ",") {
+		u = strings.TrimSpace(u)
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
+
+// URLs returns the ordered list of discovery URLs
+// The list is taken from, in order of precedence:
+//   - The environment variable EnvURLs
+//   - The discovery_urls key in the config
+//   - The default discovery URL of the variant
+func URLs() []string {
+	if urls := splitURLs(os.Getenv(EnvURLs)); len(urls) > 0 {
+		return urls
+	}
+	c, err := config.Load()
+	if err == nil && c != nil && len(c.DiscoveryURLs) > 0 {
+		return c.DiscoveryURLs
+	}
+	return []string{variant.DiscoveryURL}
 }
 
 // NewCache creates a new cache struct
-func NewCache() *Cache {
-	return &Cache{}
+// The mirrors are the comma separated discovery URLs that are tried in order
+// If no mirrors are given, the URLs are determined using URLs
+func NewCache(mirrors ...string) *Cache {
+	var urls []string
+	for _, m := range mirrors {
+		urls = append(urls, splitURLs(m)...)
+	}
+	if len(urls) == 0 {
+		urls = URLs()
+	}
+	return &Cache{URLs: urls}
+}
+
+// Load loads the cache from the config directory
+func (c *Cache) Load() error {
+	dir, err := config.Directory()
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(dir, cacheName)
+	b, err := os.ReadFile(p)
+	if err != nil {
+		slog.Debug("Error reading discovery cache", "file", p, "error", err)
+		return err
+	}
+	var nc Cache
+	err = json.Unmarshal(b, &nc)
+	if err != nil {
+		slog.Debug("Error loading discovery cache", "file", p, "error", err)
+		return err
+	}
+	// the URLs and client are not stored on disk
+	nc.URLs = c.URLs
+	nc.Client = c.Client
+	*c = nc
+	return nil
+}
+
+// Write writes the cache to the config directory
+func (c *Cache) Write() error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		slog.Debug("Error marshalling discovery cache", "error", err)
+		return err
+	}
+	_, err = config.WriteFile(cacheName, b)
+	if err != nil {
+		slog.Debug("Error writing discovery cache", "file", cacheName, "error", err)
+	}
+	return err
+}
+
+// client returns the HTTP client for the requests
+func (c *Cache) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return httpclient.Client
+}
+
+// urls returns the discovery URLs that are tried when updating
+func (c *Cache) urls() []string {
+	if len(c.URLs) == 0 {
+		return URLs()
+	}
+	return c.URLs
+}
+
+// sameMirrors returns whether the cached list was fetched with the currently configured mirrors
+// After a switch to other mirrors, e.g. a private mirror, the seq of the cached list cannot be compared with the new mirrors
+func (c *Cache) sameMirrors() bool {
+	return slices.Equal(c.Mirrors, c.urls())
 }
 
 // ToUpdate returns whether or not we should update the cached list
+// The list is always updated if the configured mirrors changed
 func (c *Cache) ToUpdate() bool {
-	if c.LastUpdate.IsZero() {
+	if c.LastUpdate.IsZero() || !c.sameMirrors() {
 		return true
 	}
+	n := time.Now()
+	if !c.Expires.IsZero() {
+		return n.After(c.Expires)
+	}
 	// We update every hour
 	u := c.LastUpdate.Add(1 * time.Hour)
-	n := time.Now()
 	return n.After(u)
 }
 
-// Providers gets the providers either from the cache or from scratch
-func (c *Cache) Providers() (*provider.Providers, error) {
-	if !c.ToUpdate() {
-		return &c.Cached.Value.Providers, nil
+// maxAge parses the max-age directive from a Cache-Control header
+// It returns false if no max-age could be found
+// no-cache and no-store are returned as a max-age of zero such that we always revalidate
+func maxAge(cc string) (time.Duration, bool) {
+	for _, d := range strings.Split(cc, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "no-cache" || d == "no-store" {
+			return 0, true
+		}
+		v, ok := strings.CutPrefix(d, "max-age=")
+		if !ok {
+			continue
+		}
+		secs, err := strconv.Atoi(strings.Trim(v, `"`))
+		if err != nil || secs < 0 {
+			slog.Debug("Invalid max-age in Cache-Control header", "header", cc)
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	return 0, false
+}
+
+// noStore returns whether a Cache-Control header has the no-store directive
+func noStore(cc string) bool {
+	for _, d := range strings.Split(cc, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// updateValidity updates the freshness of the cache using the response headers
+// The max-age is capped at maxExpiry
+func (c *Cache) updateValidity(res *http.Response) {
+	cc := res.Header.Get("Cache-Control")
+	c.LastUpdate = time.Now()
+	c.Expires = time.Time{}
+	c.noStore = noStore(cc)
+	if age, ok := maxAge(cc); ok {
+		c.Expires = c.LastUpdate.Add(min(age, maxExpiry))
 	}
+}
 
-	req, err := http.NewRequest("GET", variant.DiscoveryURL, nil)
+// update updates the cached list by fetching discovery from url
+// If we have a cached list, the request is made conditional using the stored ETag and Last-Modified validators
+func (c *Cache) update(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return &c.Cached.Value.Providers, err
+		return err
+	}
+	// Only revalidate if we have something to fall back to from the same mirror
+	// The validators of one mirror mean nothing to another
+	if len(c.Cached.Value.Providers) > 0 && c.Source == url {
+		if c.ETag != "" {
+			req.Header.Set("If-None-Match", c.ETag)
+		}
+		if c.LastModified != "" {
+			req.Header.Set("If-Modified-Since", c.LastModified)
+		}
 	}
 
 	// Do request
-	client := http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
+	res, err := c.client().Do(req)
 	if err != nil {
 		slog.Debug("Error requesting discovery.json", "error", err)
-		return &c.Cached.Value.Providers, err
+		return err
 	}
 	defer res.Body.Close() //nolint:errcheck
 
+	// The cached list is still up to date
+	if res.StatusCode == http.StatusNotModified {
+		c.Mirrors = slices.Clone(c.urls())
+		slog.Debug("Discovery not modified, using the cached list")
+		c.updateValidity(res)
+		return nil
+	}
+
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
 		slog.Debug("Error reading discovery.json response", "error", err)
-		return &c.Cached.Value.Providers, err
+		return err
 	}
 	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return &c.Cached.Value.Providers, fmt.Errorf("status code is not 2xx for discovery. Status code: %v, body: %v", res.StatusCode, string(body))
+		return fmt.Errorf("status code is not 2xx for discovery. Status code: %v, body: %v", res.StatusCode, string(body))
 	}
 
 	var d *Discovery
 	err = json.Unmarshal(body, &d)
 	if err != nil {
 		slog.Debug("Error loading discovery.json", "error", err)
-		return &c.Cached.Value.Providers, err
+		return err
 	}
 
 	// Do not accept older versions
 	// This happens if the cached version is higher
-	if c.Cached.Value.Seq > d.Value.Seq {
-		return &c.Cached.Value.Providers, fmt.Errorf("cached seq is higher")
+	// The mirrors of one list are expected to be in sync, a list from other mirrors can have an unrelated seq
+	if c.sameMirrors() && c.Cached.Value.Seq > d.Value.Seq {
+		return fmt.Errorf("cached seq %d is higher than seq %d of %s", c.Cached.Value.Seq, d.Value.Seq, url)
 	}
 
 	c.Cached = *d
-	c.LastUpdate = time.Now()
-	return &d.Value.Providers, nil
+	c.ETag = res.Header.Get("ETag")
+	c.LastModified = res.Header.Get("Last-Modified")
+	c.Source = url
+	c.Mirrors = slices.Clone(c.urls())
+	c.updateValidity(res)
+	return nil
+}
+
+// updateMirrors updates the cached list by trying each discovery URL in turn until one succeeds
+// If every mirror fails, the errors of all mirrors are returned
+func (c *Cache) updateMirrors(ctx context.Context) error {
+	urls := c.urls()
+	var errs []error
+	for _, u := range urls {
+		err := c.update(ctx, u)
+		if err == nil {
+			return nil
+		}
+		// the caller is no longer interested, do not try the other mirrors
+		if ctx.Err() != nil {
+			return err
+		}
+		slog.Warn("Failed to update discovery from mirror", "url", u, "error", err)
+		errs = append(errs, fmt.Errorf("discovery mirror %s: %w", u, err))
+	}
+	return errors.Join(errs...)
+}
+
+// Providers gets the providers either from the cache or from scratch
+// If discovery cannot be updated but a previous list was cached, the cached list is returned without an error
+// The context can be used to cancel the request or to set a deadline
+func (c *Cache) Providers(ctx context.Context) (*provider.Providers, error) {
+	// Nothing in memory yet, try to get the list from disk
+	if c.LastUpdate.IsZero() {
+		if err := c.Load(); err != nil {
+			slog.Debug("No discovery cache loaded from disk", "error", err)
+		}
+	}
+	if !c.ToUpdate() {
+		return &c.Cached.Value.Providers, nil
+	}
+
+	err := c.updateMirrors(ctx)
+	if err != nil {
+		if len(c.Cached.Value.Providers) == 0 || ctx.Err() != nil {
+			return &c.Cached.Value.Providers, err
+		}
+		slog.Warn("Failed to update discovery, using the cached list", "error", err, "updated", c.LastUpdate)
+		return &c.Cached.Value.Providers, nil
+	}
+
+	// the server does not want the list to be stored, it is only kept in memory
+	if c.noStore {
+		slog.Debug("Not writing the discovery cache as the response has Cache-Control no-store")
+		return &c.Cached.Value.Providers, nil
+	}
+	if err := c.Write(); err != nil {
+		slog.Warn("Failed to write the discovery cache", "error", err)
+	}
+	return &c.Cached.Value.Providers, nil
 }
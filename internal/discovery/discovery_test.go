@@ -0,0 +1,291 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/geteduroam/linux-app/internal/config"
+	"github.com/geteduroam/linux-app/internal/provider"
+	"github.com/geteduroam/linux-app/internal/variant"
+)
+
+func mockDir(t *testing.T, dir string) {
+	// mock XDG_DATA_HOME
+	err := os.Setenv("XDG_DATA_HOME", dir)
+	if err != nil {
+		t.Fatalf("failed setting environment for XDG_DATA_HOME: %v", err)
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	mockDir(t, t.TempDir())
+	c := &Cache{
+		Cached: Discovery{
+			Value: Value{
+				Providers: provider.Providers{
+					{
+						ID:      "provider_one",
+						Country: "NL",
+						Name:    provider.LocalizedStrings{{Display: "Provider One", Lang: "en"}},
+					},
+				},
+				Seq: 42,
+			},
+		},
+		LastUpdate: time.Now().Round(0),
+		Mirrors:    URLs(),
+	}
+	if err := c.Write(); err != nil {
+		t.Fatalf("error occurred when writing cache: %v", err)
+	}
+
+	got := NewCache()
+	if err := got.Load(); err != nil {
+		t.Fatalf("error occurred when loading cache: %v", err)
+	}
+	if !reflect.DeepEqual(got.Cached, c.Cached) {
+		t.Fatalf("cached discovery not equal, got: %v, want: %v", got.Cached, c.Cached)
+	}
+	if !got.LastUpdate.Equal(c.LastUpdate) {
+		t.Fatalf("last update not equal, got: %v, want: %v", got.LastUpdate, c.LastUpdate)
+	}
+
+	// The cache on disk is still fresh so no request should be done
+	p, err := NewCache().Providers(context.Background())
+	if err != nil {
+		t.Fatalf("error occurred when getting providers from the disk cache: %v", err)
+	}
+	if !reflect.DeepEqual(*p, c.Cached.Value.Providers) {
+		t.Fatalf("providers not equal, got: %v, want: %v", *p, c.Cached.Value.Providers)
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	cases := []struct {
+		input string
+		want  time.Duration
+		ok    bool
+	}{
+		{input: "", want: 0, ok: false},
+		{input: "public, max-age=3600", want: time.Hour, ok: true},
+		{input: "Max-Age=60, must-revalidate", want: time.Minute, ok: true},
+		{input: "no-cache", want: 0, ok: true},
+		{input: "max-age=foo", want: 0, ok: false},
+		{input: "max-age=-1", want: 0, ok: false},
+	}
+	for _, c := range cases {
+		got, ok := maxAge(c.input)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("max age for %q not equal, got: %v, %v, want: %v, %v", c.input, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestUpdateConditional(t *testing.T) {
+	mockDir(t, t.TempDir())
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=120")
+		fmt.Fprint(w, `{"http://letswifi.app/discovery#v3": {"providers": [{"id": "provider_one"}], "seq": 1}}`)
+	}))
+	defer srv.Close()
+
+	c := NewCache()
+	if err := c.update(context.Background(), srv.URL); err != nil {
+		t.Fatalf("error occurred when updating the cache: %v", err)
+	}
+	if c.ETag != `"v1"` {
+		t.Fatalf("ETag not stored, got: %v", c.ETag)
+	}
+	if c.ToUpdate() {
+		t.Fatalf("cache should be fresh after the update")
+	}
+	if got := c.Expires.Sub(c.LastUpdate); got != 120*time.Second {
+		t.Fatalf("expires not based on max-age, got: %v", got)
+	}
+
+	// revalidate, the server says not modified
+	if err := c.update(context.Background(), srv.URL); err != nil {
+		t.Fatalf("error occurred when revalidating the cache: %v", err)
+	}
+	if got := c.Expires.Sub(c.LastUpdate); got != 60*time.Second {
+		t.Fatalf("expires not updated on not modified, got: %v", got)
+	}
+	if len(c.Cached.Value.Providers) != 1 || c.Cached.Value.Providers[0].ID != "provider_one" {
+		t.Fatalf("cached providers changed on not modified, got: %v", c.Cached.Value.Providers)
+	}
+	if requests != 2 {
+		t.Fatalf("expected 2 requests, got: %d", requests)
+	}
+}
+
+func TestUpdateValidity(t *testing.T) {
+	cc := ""
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", cc)
+		fmt.Fprint(w, `{"http://letswifi.app/discovery#v3": {"providers": [{"id": "provider_one"}], "seq": 1}}`)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		cc      string
+		expires time.Duration
+		written bool
+	}{
+		{cc: "max-age=120", expires: 120 * time.Second, written: true},
+		// a year is capped
+		{cc: "max-age=31536000", expires: maxExpiry, written: true},
+		{cc: "no-store", expires: 0, written: false},
+	}
+	for _, c := range cases {
+		mockDir(t, t.TempDir())
+		cc = c.cc
+		cache := NewCache(srv.URL)
+		if _, err := cache.Providers(context.Background()); err != nil {
+			t.Fatalf("error occurred when getting providers: %v", err)
+		}
+		if got := cache.Expires.Sub(cache.LastUpdate); got != c.expires {
+			t.Fatalf("expires for %q not equal, got: %v, want: %v", c.cc, got, c.expires)
+		}
+		err := NewCache(srv.URL).Load()
+		if written := err == nil; written != c.written {
+			t.Fatalf("cache written for %q not equal, got: %v, want: %v", c.cc, written, c.written)
+		}
+	}
+}
+
+func TestURLs(t *testing.T) {
+	mockDir(t, t.TempDir())
+	t.Setenv(EnvURLs, "")
+
+	// nothing configured, we get the default
+	if got := URLs(); !reflect.DeepEqual(got, []string{variant.DiscoveryURL}) {
+		t.Fatalf("default URLs not equal, got: %v", got)
+	}
+
+	// the config overrides the default
+	c := config.Config{DiscoveryURLs: []string{"https://config.example.org/discovery.json"}}
+	if err := c.Write(); err != nil {
+		t.Fatalf("error occurred when writing config: %v", err)
+	}
+	if got := URLs(); !reflect.DeepEqual(got, c.DiscoveryURLs) {
+		t.Fatalf("config URLs not equal, got: %v, want: %v", got, c.DiscoveryURLs)
+	}
+
+	// the environment overrides the config
+	t.Setenv(EnvURLs, "https://a.example.org/discovery.json, ,https://b.example.org/discovery.json")
+	want := []string{"https://a.example.org/discovery.json", "https://b.example.org/discovery.json"}
+	if got := URLs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("environment URLs not equal, got: %v, want: %v", got, want)
+	}
+
+	// explicit mirrors override everything
+	want = []string{"https://c.example.org/discovery.json"}
+	if got := NewCache("https://c.example.org/discovery.json").URLs; !reflect.DeepEqual(got, want) {
+		t.Fatalf("cache URLs not equal, got: %v, want: %v", got, want)
+	}
+}
+
+func TestUpdateMirrors(t *testing.T) {
+	mockDir(t, t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mirror.json" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"http://letswifi.app/discovery#v3": {"providers": [{"id": "provider_one"}], "seq": 1}}`)
+	}))
+	defer srv.Close()
+
+	c := NewCache(srv.URL+"/down.json", srv.URL+"/mirror.json")
+	p, err := c.Providers(context.Background())
+	if err != nil {
+		t.Fatalf("error occurred when getting providers from the mirrors: %v", err)
+	}
+	if len(*p) != 1 || (*p)[0].ID != "provider_one" {
+		t.Fatalf("providers not from the second mirror, got: %v", *p)
+	}
+
+	c = NewCache(srv.URL + "/down.json")
+	if err := c.updateMirrors(context.Background()); err == nil {
+		t.Fatalf("expected an error when every mirror is down")
+	}
+}
+
+func TestUpdateSwitchMirrors(t *testing.T) {
+	mockDir(t, t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/private.json" && r.Header.Get("If-None-Match") != "" {
+			t.Errorf("validators of another mirror sent to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"http://letswifi.app/discovery#v3": {"providers": [{"id": "private"}], "seq": 1}}`)
+	}))
+	defer srv.Close()
+
+	cached := Discovery{Value: Value{Providers: provider.Providers{{ID: "public"}}, Seq: 42}}
+	public := []string{srv.URL + "/public.json"}
+	cases := []struct {
+		mirrors []string
+		want    string
+		err     bool
+	}{
+		// the same mirrors with a lower seq is an older version
+		{mirrors: public, want: "public", err: true},
+		// a switch to a private mirror has an unrelated seq
+		{mirrors: []string{srv.URL + "/private.json"}, want: "private", err: false},
+	}
+	for _, c := range cases {
+		cache := NewCache(c.mirrors...)
+		cache.Cached = cached
+		cache.ETag = `"public"`
+		cache.Source = public[0]
+		cache.Mirrors = public
+		cache.LastUpdate = time.Now()
+		if !cache.ToUpdate() && !c.err {
+			t.Fatalf("cache should be updated after switching mirrors to %v", c.mirrors)
+		}
+		err := cache.update(context.Background(), c.mirrors[0])
+		if c.err != (err != nil) {
+			t.Fatalf("error for %v not as expected, got: %v, want error: %v", c.mirrors, err, c.err)
+		}
+		if got := cache.Cached.Value.Providers[0].ID; got != c.want {
+			t.Fatalf("cached provider for %v not equal, got: %v, want: %v", c.mirrors, got, c.want)
+		}
+	}
+}
+
+func TestProvidersCancel(t *testing.T) {
+	mockDir(t, t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewCache(srv.URL+"/a.json", srv.URL+"/b.json")
+	c.Client = srv.Client()
+	// even with a cached list, a cancelled request should return an error
+	c.Cached.Value.Providers = provider.Providers{{ID: "provider_one"}}
+	p, err := c.Providers(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a context cancelled error, got: %v", err)
+	}
+	if len(*p) != 1 {
+		t.Fatalf("expected the cached providers to be returned, got: %v", *p)
+	}
+}
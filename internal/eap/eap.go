@@ -6,12 +6,18 @@
 package eap
 
 import (
+	"encoding/hex"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"golang.org/x/exp/slog"
 
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/network"
 	"github.com/geteduroam/linux-app/internal/network/cert"
 	"github.com/geteduroam/linux-app/internal/network/inner"
@@ -207,7 +213,7 @@ type EAPIdentityProvider struct { //revive:disable-line:exported
 
 // EAPIdentityProviderList ...
 type EAPIdentityProviderList struct { //revive:disable-line:exported
-	EAPIdentityProvider *EAPIdentityProvider `xml:"EAPIdentityProvider"`
+	EAPIdentityProviders []*EAPIdentityProvider `xml:"EAPIdentityProvider"`
 }
 
 // Parse parses a byte array into the main EAPIdentityProviderList struct. It returns nil if error
@@ -243,6 +249,17 @@ func (p *EAPIdentityProvider) AuthMethods() ([]*AuthenticationMethod, error) {
 	return am, nil
 }
 
+// name returns the name of the authentication method for logging, e.g. peap
+func (am *AuthenticationMethod) name() string {
+	if am.EAPMethod == nil {
+		return "unknown"
+	}
+	if n := method.Type(am.EAPMethod.Type).String(); n != "" {
+		return n
+	}
+	return fmt.Sprintf("EAP type %d", am.EAPMethod.Type)
+}
+
 // preferredInnerAuthType gets the first valid inner authentication type
 func (am *AuthenticationMethod) preferredInnerAuthType() (inner.Type, error) {
 	if len(am.InnerAuthenticationMethod) < 1 {
@@ -269,13 +286,106 @@ func (am *AuthenticationMethod) preferredInnerAuthType() (inner.Type, error) {
 	return inner.None, errors.New("no viable inner authentication method found")
 }
 
+// WiredSettings returns the wired 802.1X networks, the IEEE8023 entries
+func (p *EAPIdentityProvider) WiredSettings() []network.Wired {
+	if p.CredentialApplicability == nil {
+		return nil
+	}
+	var wired []network.Wired
+	for _, i := range p.CredentialApplicability.IEEE8023 {
+		if i == nil {
+			slog.Warn("Credential applicability IEEE8023 is nil")
+			continue
+		}
+		w := network.Wired{NetworkID: strings.TrimSpace(i.NetworkID)}
+		if slices.Contains(wired, w) {
+			continue
+		}
+		wired = append(wired, w)
+	}
+	return wired
+}
+
+// ConsortiumOIs returns the valid Passpoint roaming consortium OIs as lowercase hex
+// An OI is 3 or 5 octets, e.g. 001bc50460
+func (p *EAPIdentityProvider) ConsortiumOIs() []string {
+	if p.CredentialApplicability == nil {
+		return nil
+	}
+	var ois []string
+	for _, i := range p.CredentialApplicability.IEEE80211 {
+		if i == nil || i.ConsortiumOID == "" {
+			continue
+		}
+		oi := strings.ToLower(strings.TrimSpace(i.ConsortiumOID))
+		b, err := hex.DecodeString(oi)
+		if err != nil || (len(b) != 3 && len(b) != 5) {
+			slog.Warn("Consortium OID is invalid", "consortiumOID", i.ConsortiumOID)
+			continue
+		}
+		if !slices.Contains(ois, oi) {
+			ois = append(ois, oi)
+		}
+	}
+	return ois
+}
+
+// defaultMinRSN is the MinRSNProto that is used when an entry does not specify one
+// This is what the windows and android apps do as well
+const defaultMinRSN = "CCMP"
+
+// minRSNProtos are the supported values of MinRSNProto, TKIP is missing on purpose as it is insecure
+var minRSNProtos = []string{"CCMP", "GCMP", "GCMP-256"}
+
+// ssidEntry returns the SSID for an IEEE80211 entry
+// It returns the reason why the entry was accepted, or an error with the reason why it was rejected
+func ssidEntry(i *IEEE80211) (network.SSID, string, error) {
+	if i.SSID == "" {
+		return network.SSID{}, "", errors.New("SSID is empty")
+	}
+	rsn := strings.ToUpper(strings.TrimSpace(i.MinRSNProto))
+	reason := "MinRSNProto is " + rsn
+	if rsn == "" {
+		rsn = defaultMinRSN
+		reason = "MinRSNProto is empty, defaulting to " + defaultMinRSN
+	}
+	// tkip is too insecure
+	if rsn == "TKIP" {
+		return network.SSID{}, "", errors.New("MinRSNProto TKIP is insecure")
+	}
+	if !slices.Contains(minRSNProtos, rsn) {
+		return network.SSID{}, "", fmt.Errorf("MinRSNProto %q is not supported", i.MinRSNProto)
+	}
+	return rsnSecurity(network.SSID{Value: i.SSID, MinRSN: rsn}), reason, nil
+}
+
+// rsnSecurity sets the WPA3-Enterprise settings that follow from the MinRSNProto of the SSID
+//   - CCMP networks can be WPA2 or WPA3-Enterprise transition mode, PMF is used if the network supports it
+//   - GCMP networks are WPA3-Enterprise only, PMF is required
+//   - GCMP-256 networks use the WPA3-Enterprise 192-bit mode which requires PMF
+func rsnSecurity(ssid network.SSID) network.SSID {
+	switch ssid.MinRSN {
+	case "GCMP":
+		ssid.PMF = network.PMFRequired
+	case "GCMP-256":
+		ssid.PMF = network.PMFRequired
+		ssid.SuiteB192 = true
+	default:
+		ssid.PMF = network.PMFOptional
+	}
+	return ssid
+}
+
 // SSIDSettings returns the all valid SSIDs and the MinRSNProto associated with it
 // It loops through the credential applicability list and gets all valid candidate
 // The candidate filtering was based on https://github.com/geteduroam/windows-app/blob/f11f00dee3eb71abd38537e18881463f83b180d3/EduroamConfigure/EapConfig.cs#L84
 // A candidate is valid if:
-//   - MinRSNProto is not empty, TODO: shouldn't we just default to CCMP?
 //   - The SSID is not empty
+//   - The MinRSNProto is CCMP, GCMP or GCMP-256, an empty MinRSNProto defaults to CCMP
 //   - The MinRSNProto is NOT TKIP as that is insecure
+//
+// The reason why an entry is accepted or rejected is logged,
+// if no entry is viable the returned error contains the reasons of the rejected entries
 func (p *EAPIdentityProvider) SSIDSettings() ([]network.SSID, error) {
 	if p.CredentialApplicability == nil {
 		return nil, errors.New("no Credential Applicability found")
@@ -284,36 +394,32 @@ func (p *EAPIdentityProvider) SSIDSettings() ([]network.SSID, error) {
 		return nil, errors.New("no IEE80211 section found")
 	}
 	var ssids []network.SSID
+	var rejected []string
 	for _, i := range p.CredentialApplicability.IEEE80211 {
 		if i == nil {
 			slog.Warn("Credential applicability IEEE80211 is nil")
 			continue
 		}
 
-		// no min rsn proto
-		// debug here as that seems to happen a lot
-		if i.MinRSNProto == "" {
-			slog.Debug("MinRSNProto is empty")
-			continue
-		}
-
-		// no ssid present
-		if i.SSID == "" {
-			slog.Warn("SSID is empty")
+		// a Passpoint entry, see ConsortiumOIs
+		if i.SSID == "" && i.ConsortiumOID != "" {
+			slog.Debug("Entry is a consortium OID", "consortiumOID", i.ConsortiumOID)
 			continue
 		}
 
-		// tkip is too insecure
-		if i.MinRSNProto == "TKIP" {
-			slog.Warn("MinRSNProto is not TKIP", "minRSNProto", i.MinRSNProto)
+		ssid, reason, err := ssidEntry(i)
+		if err != nil {
+			slog.Warn("Rejected SSID entry", "ssid", i.SSID, "minRSNProto", i.MinRSNProto, "reason", err)
+			rejected = append(rejected, fmt.Sprintf("SSID %q: %v", i.SSID, err))
 			continue
 		}
-		ssids = append(ssids, network.SSID{
-			Value:  i.SSID,
-			MinRSN: i.MinRSNProto,
-		})
+		slog.Debug("Accepted SSID entry", "ssid", ssid.Value, "minRSNProto", ssid.MinRSN, "reason", reason)
+		ssids = append(ssids, ssid)
 	}
 	if len(ssids) == 0 {
+		if len(rejected) > 0 {
+			return nil, fmt.Errorf("no viable SSID entries found, rejected: %s", strings.Join(rejected, "; "))
+		}
 		return nil, errors.New("no viable SSID entries found")
 	}
 	return ssids, nil
@@ -381,10 +487,35 @@ func certFromContainer(ccert string, passphrase string) (*cert.ClientCert, error
 // TLSNetwork creates a TLS network using the authentication method.
 // The base that is passed here are settings that are common between TLS and NON-TLS networks
 func (am *AuthenticationMethod) TLSNetwork(base network.Base) (network.Network, error) {
-	var ccert string
-	var passphrase string
 	var identity string
 	if csc := am.ClientSideCredential; csc != nil {
+		identity = csc.OuterIdentity
+	}
+	ccert, passphrase, fcc, err := clientCertificate(am.ClientSideCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity != "" {
+		base.AnonIdentity = identity
+	} else { // if the identity is not given in the EAP metadata, set it to the subject common name
+		base.AnonIdentity = fcc.SubjectCN()
+	}
+	return &network.TLS{
+		Base:       base,
+		ClientCert: fcc,
+		RawPKCS12:  ccert,
+		Password:   passphrase,
+	}, nil
+}
+
+// clientCertificate gets the client certificate from the client side credentials
+// It returns the raw PKCS12 container, the passphrase and the decoded certificate,
+// the certificate is nil if it is not given or if the passphrase still has to be asked from the user
+func clientCertificate(csc *ClientCredentialVariants) (string, string, *cert.ClientCert, error) {
+	var ccert string
+	var passphrase string
+	if csc != nil {
 		cc := csc.ClientCertificate
 		if cc.isValid("PKCS12") {
 			ccert = cc.Value
@@ -392,7 +523,6 @@ func (am *AuthenticationMethod) TLSNetwork(base network.Base) (network.Network,
 		} else {
 			slog.Debug("cc is not valid")
 		}
-		identity = csc.OuterIdentity
 	}
 
 	// there could be multiple things going wrong:
@@ -415,21 +545,24 @@ func (am *AuthenticationMethod) TLSNetwork(base network.Base) (network.Network,
 		slog.Debug("We found a client certificate")
 		fcc, err = certFromContainer(ccert, passphrase)
 		if err != nil {
-			return nil, err
+			return "", "", nil, err
 		}
 	}
+	return ccert, passphrase, fcc, nil
+}
 
-	if identity != "" {
-		base.AnonIdentity = identity
-	} else { // if the identity is not given in the EAP metadata, set it to the subject common name
-		base.AnonIdentity = fcc.SubjectCN()
+// innerClientCredential returns the client side credentials for the inner authentication method
+// These are the credentials of the inner method in the EAP config if it has them, otherwise the ones of the outer method
+func (am *AuthenticationMethod) innerClientCredential(it inner.Type) *ClientCredentialVariants {
+	for _, i := range am.InnerAuthenticationMethod {
+		if i == nil || i.EAPMethod == nil || i.ClientSideCredential == nil {
+			continue
+		}
+		if inner.Type(i.EAPMethod.Type) == it {
+			return i.ClientSideCredential
+		}
 	}
-	return &network.TLS{
-		Base:       base,
-		ClientCert: fcc,
-		RawPKCS12:  ccert,
-		Password:   passphrase,
-	}, nil
+	return am.ClientSideCredential
 }
 
 // NonTLSNetwork creates a network that is Non-TLS using the authentication method
@@ -451,57 +584,110 @@ func (am *AuthenticationMethod) NonTLSNetwork(base network.Base) (network.Networ
 		}
 
 	}
-	// get the inner auth
-	it, err := am.preferredInnerAuthType()
-	if err != nil {
-		return nil, errors.New("no preferred inner authentication found")
+	// get the inner auth, EAP-PWD has none
+	it := inner.None
+	if method.Type(am.EAPMethod.Type).HasInner() {
+		var err error
+		it, err = am.preferredInnerAuthType()
+		if err != nil {
+			return nil, fmt.Errorf("no preferred inner authentication found: %w", err)
+		}
 	}
 
 	base.AnonIdentity = identity
 
 	// Configure the credentials and associated metadata
 	c := network.Credentials{
-		Username: username,
-		Prefix:   prefix,
-		Suffix:   suffix,
-		Password: password,
+		Username:     username,
+		Prefix:       prefix,
+		Suffix:       suffix,
+		Password:     password,
+		OneTimeToken: it.NeedsToken(),
 	}
 
-	return &network.NonTLS{
+	n := &network.NonTLS{
 		Base:        base,
 		Credentials: c,
 		MethodType:  method.Type(am.EAPMethod.Type),
 		InnerAuth:   it,
-	}, nil
+	}
+	// EAP-FAST needs a PAC, either from the EAP config or provisioned when connecting
+	if n.MethodType == method.FAST {
+		if cc := am.ClientSideCredential; cc != nil {
+			n.PAC = strings.TrimSpace(cc.PAC)
+			n.ProvisionPAC = cc.ProvisionPAC
+		}
+		if n.PAC == "" && !n.ProvisionPAC {
+			return nil, errors.New("EAP-FAST has no PAC and PAC provisioning is not allowed")
+		}
+		// Without CAs the PAC can only be provisioned unauthenticated, which is only safe with EAP-MSCHAPv2
+		if n.ProvisionPAC && len(base.Certs) == 0 {
+			switch {
+			case n.PAC != "":
+				n.ProvisionPAC = false
+			case it != inner.EapMschapv2 && it != inner.EapPeapMschapv2:
+				return nil, errors.New("EAP-FAST has no PAC and no server side CA, unauthenticated PAC provisioning is only allowed with EAP-MSCHAPv2")
+			}
+		}
+	}
+	// TTLS with inner EAP-TLS authenticates with a client certificate instead of a password
+	if it.NeedsCertificate() {
+		ccert, passphrase, fcc, err := clientCertificate(am.innerClientCredential(it))
+		if err != nil {
+			return nil, err
+		}
+		n.RawInnerPKCS12 = ccert
+		n.InnerPassphrase = passphrase
+		n.InnerCert = fcc
+		// the inner identity defaults to the subject common name, like the identity of TLS
+		if n.Credentials.Username == "" && fcc != nil {
+			n.Credentials.Username = fcc.SubjectCN()
+		}
+	}
+	return n, nil
 }
 
-// Network gets a network for an authentication method, the SSID and MinRSN are strings that are based to the network
-func (am *AuthenticationMethod) Network(ssids []network.SSID, pinfo network.ProviderInfo) (network.Network, error) {
+// Network gets a network for an authentication method
+// The provider is the base with the settings of the identity provider, e.g. the SSIDs and provider info,
+// the settings of the authentication method are added to it
+func (am *AuthenticationMethod) Network(provider network.Base) (network.Network, error) {
 	// We check if the eap method is valid
-	if am.EAPMethod == nil || !method.IsValid(am.EAPMethod.Type) {
+	if am.EAPMethod == nil {
 		return nil, errors.New("no EAP method")
 	}
+	if !method.IsValid(am.EAPMethod.Type) {
+		return nil, fmt.Errorf("EAP method %d is not supported", am.EAPMethod.Type)
+	}
 	mt := am.EAPMethod.Type
 
+	// Create the Base
+	// These are the settings that are common for each network
+	base := provider
+
 	// Get the server side credentials
 	ss := am.ServerSideCredential
-	if ss == nil {
+	switch {
+	// e.g. EAP-PWD has no server side certificates to verify and for EAP-FAST they are optional
+	case !method.Type(mt).NeedsServerCA():
+		if ss == nil || len(ss.CA) == 0 {
+			break
+		}
+		// the CAs are optional but if they are given they must be valid, e.g. EAP-FAST uses them to verify the server when provisioning the PAC
+		CA, err := ss.CAList()
+		if err != nil {
+			return nil, fmt.Errorf("invalid server side CA: %w", err)
+		}
+		base.Certs = CA
+		base.ServerIDs = ss.ServerID
+	case ss == nil:
 		return nil, errors.New("no server side credentials")
-	}
-
-	CA, err := ss.CAList()
-	if err != nil {
-		return nil, errors.New("no preferred server side CA found")
-	}
-
-	// Create the Base
-	// These are the settings that are common for each network
-	sid := ss.ServerID
-	base := network.Base{
-		Certs:        CA,
-		ProviderInfo: pinfo,
-		SSIDs:        ssids,
-		ServerIDs:    sid,
+	default:
+		CA, err := ss.CAList()
+		if err != nil {
+			return nil, fmt.Errorf("no preferred server side CA found: %w", err)
+		}
+		base.Certs = CA
+		base.ServerIDs = ss.ServerID
 	}
 
 	// If TLS we need to construct different arguments than when we have Non TLS
@@ -511,51 +697,63 @@ func (am *AuthenticationMethod) Network(ssids []network.SSID, pinfo network.Prov
 	return am.NonTLSNetwork(base)
 }
 
-// Logo returns the logo for the provider info elements
-// If the logo is an unexpected type: nil, no PNG, no base64, we return an empty string and an error
-// TODO: Note that right now we assume that every logo is a base64 and PNG, we need to determine if this is the only possible type that will be returned
-func (pi *ProviderInfoElements) Logo() (string, error) {
-	if pi.ProviderLogo == nil {
-		return "", errors.New("no provider logo found")
-	}
-	data := *pi.ProviderLogo
-	if data.MimeAttr != "image/png" {
-		return "", errors.New("logo is not a PNG image")
+// Location parses the latitude and longitude of the location element
+func (le *LocationElements) Location() (network.Location, error) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(le.Latitude), 64)
+	if err != nil {
+		return network.Location{}, fmt.Errorf("invalid latitude: %w", err)
 	}
-	if data.EncodingAttr != "base64" {
-		return "", errors.New("image is not base64")
+	lon, err := strconv.ParseFloat(strings.TrimSpace(le.Longitude), 64)
+	if err != nil {
+		return network.Location{}, fmt.Errorf("invalid longitude: %w", err)
 	}
-	return data.Value, nil
+	return network.NewLocation(lat, lon)
 }
 
-// LocalizedInteractiveValue gets the first non-nil value from the localized interactive slice
+// LocalizedInteractiveValue gets the value from the localized interactive slice that best matches the languages of the user
+// If no value matches the languages, the first non-nil value is returned
 // if no value is available, it returns an error
 func LocalizedInteractiveValue(slice []*LocalizedInteractive) (string, error) {
 	if len(slice) == 0 {
 		return "", errors.New("no interactive localized value available")
 	}
+	langs := make([]string, len(slice))
+	for i, v := range slice {
+		if v != nil {
+			langs[i] = v.LangAttr
+		}
+	}
+	if i, ok := lang.Best(langs); ok {
+		return slice[i].Value, nil
+	}
 	for _, v := range slice {
-		if v == nil {
-			continue
+		if v != nil {
+			return v.Value, nil
 		}
-		// TODO: What is LangAttr used for?
-		return v.Value, nil
 	}
 	return "", errors.New("all interactive localized values are nil")
 }
 
-// LocalizedNonInteractiveValue gets the first non-nil value from the localized non interactive slice
+// LocalizedNonInteractiveValue gets the value from the localized non interactive slice that best matches the languages of the user
+// If no value matches the languages, the first non-nil value is returned
 // if no value is available, it returns an error
 func LocalizedNonInteractiveValue(slice []*LocalizedNonInteractive) (string, error) {
 	if len(slice) == 0 {
 		return "", errors.New("no non interactive localized value available")
 	}
+	langs := make([]string, len(slice))
+	for i, v := range slice {
+		if v != nil {
+			langs[i] = v.LangAttr
+		}
+	}
+	if i, ok := lang.Best(langs); ok {
+		return slice[i].Value, nil
+	}
 	for _, v := range slice {
-		if v == nil {
-			continue
+		if v != nil {
+			return v.Value, nil
 		}
-		// TODO: What is LangAttr used for?
-		return v.Value, nil
 	}
 	return "", errors.New("all non interactive localized values are nil")
 }
@@ -570,8 +768,23 @@ func (p *EAPIdentityProvider) PInfo() network.ProviderInfo {
 	if pi != nil {
 		pinfo.Name, _ = LocalizedNonInteractiveValue(pi.DisplayName)
 		pinfo.Description, _ = LocalizedNonInteractiveValue(pi.Description)
-		pinfo.Logo, _ = p.ProviderInfo.Logo()
+		logo, err := pi.Logo()
+		if err != nil {
+			slog.Debug("Ignoring provider logo", "error", err)
+		}
+		pinfo.Logo = logo
 		pinfo.Terms, _ = LocalizedNonInteractiveValue(pi.TermsOfUse)
+		for _, le := range pi.ProviderLocation {
+			if le == nil {
+				continue
+			}
+			l, err := le.Location()
+			if err != nil {
+				slog.Debug("Ignoring invalid provider location", "error", err)
+				continue
+			}
+			pinfo.Locations = append(pinfo.Locations, l)
+		}
 		desk := p.ProviderInfo.Helpdesk
 		if desk != nil {
 			help.Email, _ = LocalizedInteractiveValue(desk.EmailAddress)
@@ -585,33 +798,111 @@ func (p *EAPIdentityProvider) PInfo() network.ProviderInfo {
 	return pinfo
 }
 
-// Network creates a TLS or NON-TLS secured network from the EAP config
-// This network can then afterwards be imported into NetworkManager using the `nm` package
-func (eap *EAPIdentityProviderList) Network() (network.Network, error) {
-	// Get the provider section
-	p := eap.EAPIdentityProvider
-	if p == nil {
-		slog.Debug("The identity provider section is nil")
-		return nil, errors.New("identity provider section couldn't be found")
+// validUntilLayouts are the layouts of the xs:dateTime ValidUntil value, with and without a timezone
+var validUntilLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Expiry parses the ValidUntil element of the identity provider
+// A ValidUntil without a timezone is interpreted as UTC
+// It returns nil if the ValidUntil element is not given
+func (p *EAPIdentityProvider) Expiry() (*time.Time, error) {
+	v := strings.TrimSpace(p.ValidUntil)
+	if v == "" {
+		return nil, nil
+	}
+	for _, l := range validUntilLayouts {
+		t, err := time.Parse(l, v)
+		if err == nil {
+			return &t, nil
+		}
 	}
+	return nil, fmt.Errorf("invalid ValidUntil: %q", p.ValidUntil)
+}
+
+// Networks creates the TLS or NON-TLS secured networks for the identity provider
+// One network is returned for each viable authentication method in the order of the EAP config, which is the order of preference
+// The reason why an authentication method is rejected is logged,
+// if no method is viable the returned error contains the reasons of the rejected methods
+func (p *EAPIdentityProvider) Networks() ([]network.Network, error) {
 	methods, err := p.AuthMethods()
 	if err != nil {
 		slog.Debug("Error getting AuthMethods", "error", err)
 		return nil, err
 	}
+	wired := p.WiredSettings()
 	ssids, err := p.SSIDSettings()
 	if err != nil {
 		slog.Debug("Error getting SSIDSettings", "error", err)
-		return nil, err
+		// a config for wired networks only is fine
+		if len(wired) == 0 {
+			return nil, err
+		}
+	}
+	validUntil, err := p.Expiry()
+	if err != nil {
+		// we do not want to reject the whole config for this
+		slog.Debug("Ignoring invalid ValidUntil", "error", err)
 	}
-	pinfo := p.PInfo()
-	for _, m := range methods {
-		n, err := m.Network(ssids, pinfo)
+	base := network.Base{
+		ProviderInfo:  p.PInfo(),
+		SSIDs:         ssids,
+		Wired:         wired,
+		ConsortiumOIs: p.ConsortiumOIs(),
+		ValidUntil:    validUntil,
+	}
+	var ns []network.Network
+	var rejected []string
+	for i, m := range methods {
+		if m == nil {
+			continue
+		}
+		n, err := m.Network(base)
 		if err != nil {
-			slog.Error("Error getting ProviderInfo", "error", err)
+			slog.Warn("Rejected authentication method", "index", i, "method", m.name(), "reason", err)
+			rejected = append(rejected, fmt.Sprintf("%s: %v", m.name(), err))
 			continue
 		}
-		return n, nil
+		slog.Debug("Accepted authentication method", "index", i, "method", n.MethodName())
+		ns = append(ns, n)
+	}
+	if len(ns) == 0 {
+		if len(rejected) > 0 {
+			return nil, fmt.Errorf("no viable authentication method found in EAP config, rejected: %s", strings.Join(rejected, "; "))
+		}
+		return nil, errors.New("no viable network settings found in EAP config")
+	}
+	return ns, nil
+}
+
+// Networks creates the TLS or NON-TLS secured networks from the EAP config
+// An EAP config can contain multiple identity providers, the networks of each viable provider are returned in preference order
+// The user can then choose which provider and method to import into NetworkManager using the `nm` package
+// If no provider is viable, the error of the first provider is returned
+func (eap *EAPIdentityProviderList) Networks() ([][]network.Network, error) {
+	var ns [][]network.Network
+	var first error
+	for i, p := range eap.EAPIdentityProviders {
+		if p == nil {
+			continue
+		}
+		n, err := p.Networks()
+		if err != nil {
+			slog.Debug("Skipping identity provider", "index", i, "id", p.IDAttr, "error", err)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		ns = append(ns, n)
+	}
+	if len(ns) > 0 {
+		return ns, nil
+	}
+	if first != nil {
+		return nil, first
 	}
-	return nil, errors.New("no viable network settings found in EAP config")
+	slog.Debug("The identity provider section is nil")
+	return nil, errors.New("identity provider section couldn't be found")
 }
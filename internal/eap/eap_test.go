@@ -1,11 +1,22 @@
 package eap
 
 import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"golang.org/x/text/language"
+
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/network"
 	"github.com/geteduroam/linux-app/internal/network/cert"
 	"github.com/geteduroam/linux-app/internal/network/inner"
@@ -105,11 +116,16 @@ func TestParse(t *testing.T) {
 			providerInfoTest: network.ProviderInfo{
 				Name:        "eduroam Visitor Access (eVA)",
 				Description: "eVA",
+				Locations: []network.Location{{
+					Latitude:  52.0890566,
+					Longitude: 5.1134653999999955,
+				}},
 			},
 			ssidTest: ssidSettingsTest{
 				SSIDs: []network.SSID{{
 					Value:  "eduroam",
 					MinRSN: "CCMP",
+					PMF:    network.PMFOptional,
 				}},
 				err: "",
 			},
@@ -122,13 +138,19 @@ func TestParse(t *testing.T) {
 						SSIDs: []network.SSID{{
 							Value:  "eduroam",
 							MinRSN: "CCMP",
+							PMF:    network.PMFOptional,
 						}},
+						ConsortiumOIs: []string{"001bc50460", "004096"},
 						ServerIDs: []string{
 							"edu.nl",
 						},
 						ProviderInfo: network.ProviderInfo{
 							Name:        "eduroam Visitor Access (eVA)",
 							Description: "eVA",
+							Locations: []network.Location{{
+								Latitude:  52.0890566,
+								Longitude: 5.1134653999999955,
+							}},
 						},
 					},
 					Credentials: network.Credentials{
@@ -184,10 +206,10 @@ func TestParse(t *testing.T) {
 		if err != nil {
 			t.Fatalf("failed parsing file: %v", err)
 		}
-		eip := eipl.EAPIdentityProvider
-		if eip == nil {
-			t.Fatalf("no eap identity provider found")
+		if len(eipl.EAPIdentityProviders) != 1 || eipl.EAPIdentityProviders[0] == nil {
+			t.Fatalf("no single eap identity provider found")
 		}
+		eip := eipl.EAPIdentityProviders[0]
 
 		// test the individual components that make up the network
 		testAuthMethod(t, eip, c.authMethodTests)
@@ -195,7 +217,15 @@ func TestParse(t *testing.T) {
 		testSSIDSettings(t, eip, c.ssidTest)
 
 		// finally test the whole network we get back
-		n, err := eipl.Network()
+		ns, err := eipl.Networks()
+		if len(ns) > 1 {
+			t.Fatalf("more than one identity provider found: %v", ns)
+		}
+		// the preferred authentication method
+		var n network.Network
+		if len(ns) == 1 {
+			n = ns[0][0]
+		}
 		errS := utilsx.ErrorString(err)
 		if errS != c.netTest.err {
 			t.Fatalf("network error not equal. Got: %v, want: %v", errS, c.netTest.err)
@@ -280,3 +310,584 @@ func TestCertFromContainer(t *testing.T) {
 		}
 	}
 }
+
+func TestPInfoLocalized(t *testing.T) {
+	pi := &EAPIdentityProvider{
+		ProviderInfo: &ProviderInfoElements{
+			DisplayName: []*LocalizedNonInteractive{
+				{Value: "University"},
+				{LangAttr: "nl", Value: "Universiteit"},
+				{LangAttr: "de", Value: "Universität"},
+			},
+			Description: []*LocalizedNonInteractive{
+				nil,
+				{LangAttr: "en", Value: "Description"},
+				{LangAttr: "nl", Value: "Beschrijving"},
+			},
+			Helpdesk: &HelpdeskDetailElements{
+				EmailAddress: []*LocalizedInteractive{
+					{LangAttr: "en", Value: "help@example.org"},
+					{LangAttr: "de", Value: "hilfe@example.org"},
+				},
+			},
+		},
+	}
+	cases := []struct {
+		lang language.Tag
+		name string
+		desc string
+		mail string
+	}{
+		{lang: language.English, name: "University", desc: "Description", mail: "help@example.org"},
+		{lang: language.Dutch, name: "Universiteit", desc: "Beschrijving", mail: "help@example.org"},
+		{lang: language.German, name: "Universität", desc: "Description", mail: "hilfe@example.org"},
+		{lang: language.MustParse("nl-BE"), name: "Universiteit", desc: "Beschrijving", mail: "help@example.org"},
+	}
+	prev := lang.System()
+	defer lang.Set(prev)
+	for _, c := range cases {
+		lang.Set(c.lang)
+		got := pi.PInfo()
+		if got.Name != c.name {
+			t.Fatalf("name not equal for language: %v, want: %v, got: %v", c.lang, c.name, got.Name)
+		}
+		if got.Description != c.desc {
+			t.Fatalf("description not equal for language: %v, want: %v, got: %v", c.lang, c.desc, got.Description)
+		}
+		if got.Helpdesk.Email != c.mail {
+			t.Fatalf("e-mail not equal for language: %v, want: %v, got: %v", c.lang, c.mail, got.Helpdesk.Email)
+		}
+	}
+}
+
+func encodeImage(t *testing.T, w int, h int, enc func(io.Writer, image.Image) error) string {
+	var buf bytes.Buffer
+	if err := enc(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestLogo(t *testing.T) {
+	jpg := func(w io.Writer, m image.Image) error {
+		return jpeg.Encode(w, m, nil)
+	}
+	smallPNG := encodeImage(t, 10, 10, png.Encode)
+	smallJPEG := encodeImage(t, 10, 10, jpg)
+	svg := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+	cases := []struct {
+		logo     *LogoData
+		wantMime string
+		err      string
+	}{
+		{
+			logo: nil,
+			err:  "no provider logo found",
+		},
+		{
+			logo:     &LogoData{MimeAttr: "image/png", EncodingAttr: "base64", Value: smallPNG},
+			wantMime: "image/png",
+		},
+		{
+			// wrapped over multiple lines
+			logo:     &LogoData{MimeAttr: "image/png", EncodingAttr: "base64", Value: smallPNG[:20] + "\n  " + smallPNG[20:]},
+			wantMime: "image/png",
+		},
+		{
+			logo:     &LogoData{MimeAttr: "image/jpg", EncodingAttr: "base64", Value: smallJPEG},
+			wantMime: "image/jpeg",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/png", EncodingAttr: "base64", Value: smallJPEG},
+			err:  "logo is a jpeg image but the MIME type is \"image/png\"",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/png", EncodingAttr: "base64", Value: encodeImage(t, 3000, 1, png.Encode)},
+			err:  "logo dimensions 3000x1 exceed the maximum of 2048x2048",
+		},
+		{
+			logo:     &LogoData{MimeAttr: "image/svg+xml", EncodingAttr: "base64", Value: svg(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" width="100px" height="50"></svg>`)},
+			wantMime: "image/svg+xml",
+		},
+		{
+			logo:     &LogoData{MimeAttr: "image/svg+xml", EncodingAttr: "base64", Value: svg(`<svg xmlns="http://www.w3.org/2000/svg" width="100%" viewBox="0 0 200 100"></svg>`)},
+			wantMime: "image/svg+xml",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/svg+xml", EncodingAttr: "base64", Value: svg(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100000 10"></svg>`)},
+			err:  "logo dimensions 100000x10 exceed the maximum of 2048x2048",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/svg+xml", EncodingAttr: "base64", Value: svg(`<html></html>`)},
+			err:  "logo root element \"html\" is not svg",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/gif", EncodingAttr: "base64", Value: smallPNG},
+			err:  "logo MIME type \"image/gif\" is not supported",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/png", EncodingAttr: "hex", Value: smallPNG},
+			err:  "image is not base64",
+		},
+		{
+			logo: &LogoData{MimeAttr: "image/png", EncodingAttr: "base64", Value: strings.Repeat("A", MaxLogoSize*2)},
+			err:  "logo exceeds the maximum size of 1048576 bytes",
+		},
+	}
+	for _, c := range cases {
+		pi := &ProviderInfoElements{ProviderLogo: c.logo}
+		got, err := pi.Logo()
+		if utilsx.ErrorString(err) != c.err {
+			t.Fatalf("logo error not equal, want: %v, got: %v", c.err, err)
+		}
+		if got.Mime != c.wantMime {
+			t.Fatalf("logo MIME type not equal, want: %v, got: %v", c.wantMime, got.Mime)
+		}
+		if c.err == "" && len(got.Data) == 0 {
+			t.Fatalf("logo data is empty")
+		}
+	}
+}
+
+func TestMultipleProviders(t *testing.T) {
+	b, err := os.ReadFile(path.Join("test_data", "multi-provider.xml"))
+	if err != nil {
+		t.Fatalf("failed reading file: %v", err)
+	}
+	eipl, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed parsing file: %v", err)
+	}
+	if len(eipl.EAPIdentityProviders) != 3 {
+		t.Fatalf("identity providers not equal, want: 3, got: %d", len(eipl.EAPIdentityProviders))
+	}
+	// the second provider has no credential applicability and is skipped
+	ns, err := eipl.Networks()
+	if err != nil {
+		t.Fatalf("failed getting networks: %v", err)
+	}
+	var names []string
+	for _, n := range ns {
+		names = append(names, n[0].ProviderInfo().Name)
+	}
+	want := []string{"Campus A", "Campus B"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("networks not equal, want: %v, got: %v", want, names)
+	}
+
+	// no viable providers gives the error of the first one
+	eipl.EAPIdentityProviders = eipl.EAPIdentityProviders[1:2]
+	_, err = eipl.Networks()
+	if utilsx.ErrorString(err) != "no Credential Applicability found" {
+		t.Fatalf("error not equal, want: no Credential Applicability found, got: %v", err)
+	}
+	eipl.EAPIdentityProviders = nil
+	_, err = eipl.Networks()
+	if utilsx.ErrorString(err) != "identity provider section couldn't be found" {
+		t.Fatalf("error not equal, want: identity provider section couldn't be found, got: %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	cases := []struct {
+		input string
+		want  *time.Time
+		err   string
+	}{
+		{input: "", want: nil},
+		{input: "2030-01-02T03:04:05Z", want: ptrTime(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))},
+		{input: " 2030-01-02T03:04:05.5+02:00 ", want: ptrTime(time.Date(2030, 1, 2, 1, 4, 5, 500000000, time.UTC))},
+		{input: "2030-01-02T03:04:05", want: ptrTime(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))},
+		{input: "tomorrow", err: "invalid ValidUntil: \"tomorrow\""},
+	}
+	for _, c := range cases {
+		p := &EAPIdentityProvider{ValidUntil: c.input}
+		got, err := p.Expiry()
+		if utilsx.ErrorString(err) != c.err {
+			t.Fatalf("expiry error not equal for: %q, want: %v, got: %v", c.input, c.err, err)
+		}
+		if (got == nil) != (c.want == nil) || (got != nil && !got.Equal(*c.want)) {
+			t.Fatalf("expiry not equal for: %q, want: %v, got: %v", c.input, c.want, got)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
+
+func TestWired(t *testing.T) {
+	b, err := os.ReadFile(path.Join("test_data", "wired.xml"))
+	if err != nil {
+		t.Fatalf("failed reading file: %v", err)
+	}
+	eipl, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed parsing file: %v", err)
+	}
+	// a config with only wired networks is viable
+	ns, err := eipl.Networks()
+	if err != nil {
+		t.Fatalf("failed getting networks: %v", err)
+	}
+	if len(ns) != 1 {
+		t.Fatalf("networks not equal, want: 1, got: %d", len(ns))
+	}
+	n, ok := ns[0][0].(*network.NonTLS)
+	if !ok {
+		t.Fatalf("network is not a NonTLS network: %T", ns[0][0])
+	}
+	// duplicates are removed
+	want := []network.Wired{{NetworkID: "lab"}, {NetworkID: ""}}
+	if !reflect.DeepEqual(n.Wired, want) {
+		t.Fatalf("wired networks not equal, want: %v, got: %v", want, n.Wired)
+	}
+	if len(n.SSIDs) != 0 {
+		t.Fatalf("SSIDs not empty: %v", n.SSIDs)
+	}
+	if n.Media() != network.MediumWired {
+		t.Fatalf("media not equal, want: %v, got: %v", network.MediumWired, n.Media())
+	}
+}
+
+func TestConsortiumOIs(t *testing.T) {
+	p := &EAPIdentityProvider{
+		CredentialApplicability: &CredentialApplicabilityType{
+			IEEE80211: []*IEEE80211{
+				{SSID: "eduroam", MinRSNProto: "CCMP"},
+				{ConsortiumOID: "5A03BA0000"},
+				{ConsortiumOID: "001bc5"},
+				{ConsortiumOID: "5a03ba0000"},
+				// not hex
+				{ConsortiumOID: "eduroam"},
+				// not 3 or 5 octets
+				{ConsortiumOID: "001bc504"},
+				nil,
+			},
+		},
+	}
+	want := []string{"5a03ba0000", "001bc5"}
+	if got := p.ConsortiumOIs(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("consortium OIs not equal, want: %v, got: %v", want, got)
+	}
+	// the consortium OID entries are not SSIDs
+	ssids, err := p.SSIDSettings()
+	if err != nil {
+		t.Fatalf("failed getting SSIDs: %v", err)
+	}
+	if len(ssids) != 1 {
+		t.Fatalf("SSIDs not equal, want: 1, got: %v", ssids)
+	}
+}
+
+func TestSSIDSettings(t *testing.T) {
+	cases := []struct {
+		entries []*IEEE80211
+		want    []network.SSID
+		err     string
+	}{
+		{
+			entries: []*IEEE80211{
+				// defaults to CCMP
+				{SSID: "eduroam"},
+				{SSID: "eduroam-gcmp", MinRSNProto: "gcmp"},
+				{SSID: "eduroam-gcmp256", MinRSNProto: "GCMP-256"},
+				{SSID: "eduroam-tkip", MinRSNProto: "TKIP"},
+			},
+			want: []network.SSID{
+				{Value: "eduroam", MinRSN: "CCMP", PMF: network.PMFOptional},
+				{Value: "eduroam-gcmp", MinRSN: "GCMP", PMF: network.PMFRequired},
+				{Value: "eduroam-gcmp256", MinRSN: "GCMP-256", PMF: network.PMFRequired, SuiteB192: true},
+			},
+		},
+		{
+			entries: []*IEEE80211{
+				{SSID: "eduroam", MinRSNProto: "TKIP"},
+				{SSID: "eduroam", MinRSNProto: "WEP"},
+				{MinRSNProto: "CCMP"},
+			},
+			err: `no viable SSID entries found, rejected: SSID "eduroam": MinRSNProto TKIP is insecure; SSID "eduroam": MinRSNProto "WEP" is not supported; SSID "": SSID is empty`,
+		},
+	}
+
+	for _, c := range cases {
+		p := &EAPIdentityProvider{
+			CredentialApplicability: &CredentialApplicabilityType{
+				IEEE80211: c.entries,
+			},
+		}
+		got, err := p.SSIDSettings()
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("SSIDs not equal, want: %v, got: %v", c.want, got)
+		}
+		if errS := utilsx.ErrorString(err); errS != c.err {
+			t.Fatalf("errors not equal, want: %v, got: %v", c.err, errS)
+		}
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	b, err := os.ReadFile(path.Join("test_data", "eva-eap.xml"))
+	if err != nil {
+		t.Fatalf("failed reading file: %v", err)
+	}
+	eipl, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed parsing file: %v", err)
+	}
+	p := eipl.EAPIdentityProviders[0]
+	methodNames := func() []string {
+		ns, err := p.Networks()
+		if err != nil {
+			t.Fatalf("failed getting networks: %v", err)
+		}
+		var names []string
+		for _, n := range ns {
+			names = append(names, n.MethodName())
+		}
+		return names
+	}
+
+	// all viable methods in the order of the EAP config
+	want := []string{"peap-mschapv2", "ttls-mschapv2", "ttls-pap"}
+	if got := methodNames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("methods not equal, want: %v, got: %v", want, got)
+	}
+
+	// the rejected methods are skipped
+	ams := p.AuthenticationMethods.AuthenticationMethod
+	ams[1].EAPMethod.Type = 4
+	want = []string{"peap-mschapv2", "ttls-pap"}
+	if got := methodNames(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("methods not equal, want: %v, got: %v", want, got)
+	}
+
+	// no viable methods gives the reasons
+	ams[0].ServerSideCredential = nil
+	ams[2].InnerAuthenticationMethod = nil
+	_, err = p.Networks()
+	wantErr := "no viable authentication method found in EAP config, rejected: peap: no server side credentials; EAP type 4: EAP method 4 is not supported; ttls: no preferred inner authentication found: the authentication method has no inner authentication methods"
+	if utilsx.ErrorString(err) != wantErr {
+		t.Fatalf("error not equal, want: %v, got: %v", wantErr, err)
+	}
+}
+
+func TestPWD(t *testing.T) {
+	b, err := os.ReadFile(path.Join("test_data", "pwd-eap.xml"))
+	if err != nil {
+		t.Fatalf("failed reading file: %v", err)
+	}
+	eipl, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed parsing file: %v", err)
+	}
+	// EAP-PWD has no server side credentials and no inner authentication method
+	ns, err := eipl.Networks()
+	if err != nil {
+		t.Fatalf("failed getting networks: %v", err)
+	}
+	want := &network.NonTLS{
+		Base: network.Base{
+			SSIDs: []network.SSID{{
+				Value:  "eduroam",
+				MinRSN: "CCMP",
+				PMF:    network.PMFOptional,
+			}},
+			ProviderInfo: network.ProviderInfo{
+				Name: "Example EAP-PWD University",
+			},
+		},
+		Credentials: network.Credentials{
+			Suffix: "@pwd.example.org",
+		},
+		MethodType: method.PWD,
+		InnerAuth:  inner.None,
+	}
+	if len(ns) != 1 || len(ns[0]) != 1 {
+		t.Fatalf("networks not equal, want: 1, got: %v", ns)
+	}
+	if !reflect.DeepEqual(ns[0][0], want) {
+		t.Fatalf("network not equal, want: %v, got: %v", want, ns[0][0])
+	}
+	if got := ns[0][0].MethodName(); got != "pwd" {
+		t.Fatalf("method name not equal, want: pwd, got: %v", got)
+	}
+}
+
+func TestInnerMethods(t *testing.T) {
+	pkcs12, err := os.ReadFile(path.Join("test_data", "pkcs12empty"))
+	if err != nil {
+		t.Fatalf("failed reading cert file: %v", err)
+	}
+	am := func(mt int, it int, csc *ClientCredentialVariants) *AuthenticationMethod {
+		return &AuthenticationMethod{
+			EAPMethod: &EAPMethod{Type: mt},
+			InnerAuthenticationMethod: []*InnerAuthenticationMethod{{
+				EAPMethod:            &EAPMethod{Type: it},
+				ClientSideCredential: csc,
+			}},
+		}
+	}
+
+	// EAP-GTC uses a one-time token
+	for _, mt := range []method.Type{method.TTLS, method.PEAP} {
+		n, err := am(int(mt), int(inner.EapGtc), nil).NonTLSNetwork(network.Base{})
+		if err != nil {
+			t.Fatalf("failed getting network for %v: %v", mt, err)
+		}
+		nt := n.(*network.NonTLS)
+		if !nt.Credentials.OneTimeToken {
+			t.Fatalf("credentials for %v are not a one-time token", mt)
+		}
+		if want := mt.String() + "-gtc"; nt.MethodName() != want {
+			t.Fatalf("method name not equal, want: %v, got: %v", want, nt.MethodName())
+		}
+	}
+
+	// TTLS with inner EAP-TLS uses the client certificate of the inner method
+	csc := &ClientCredentialVariants{
+		ClientCertificate: &CertData{FormatAttr: "PKCS12", EncodingAttr: "base64", Value: string(pkcs12)},
+	}
+	n, err := am(int(method.TTLS), int(inner.EapTLS), csc).NonTLSNetwork(network.Base{})
+	if err != nil {
+		t.Fatalf("failed getting network for inner EAP-TLS: %v", err)
+	}
+	nt := n.(*network.NonTLS)
+	if nt.InnerCert == nil {
+		t.Fatalf("no inner client certificate")
+	}
+	if nt.Credentials.Username != nt.InnerCert.SubjectCN() {
+		t.Fatalf("username not equal, want: %v, got: %v", nt.InnerCert.SubjectCN(), nt.Credentials.Username)
+	}
+	if nt.Credentials.OneTimeToken {
+		t.Fatalf("credentials for inner EAP-TLS are a one-time token")
+	}
+	if nt.MethodName() != "ttls-tls" {
+		t.Fatalf("method name not equal, want: ttls-tls, got: %v", nt.MethodName())
+	}
+
+	// PEAP does not support inner EAP-TLS
+	_, err = am(int(method.PEAP), int(inner.EapTLS), csc).NonTLSNetwork(network.Base{})
+	if utilsx.ErrorString(err) != "no preferred inner authentication found: no viable inner authentication method found" {
+		t.Fatalf("error not equal, want: no preferred inner authentication found: no viable inner authentication method found, got: %v", err)
+	}
+}
+
+func TestFAST(t *testing.T) {
+	cases := []struct {
+		csc       *ClientCredentialVariants
+		ssc       *ServerCredentialVariants
+		inner     inner.Type
+		pac       string
+		provision bool
+		err       string
+	}{
+		{
+			csc:   nil,
+			inner: inner.EapMschapv2,
+			err:   "EAP-FAST has no PAC and PAC provisioning is not allowed",
+		},
+		{
+			csc:       &ClientCredentialVariants{ProvisionPAC: true},
+			inner:     inner.EapMschapv2,
+			provision: true,
+		},
+		{
+			csc:   &ClientCredentialVariants{PAC: "\n  pac data\n"},
+			inner: inner.EapMschapv2,
+			pac:   "pac data",
+		},
+		// the PAC is used instead of provisioning unauthenticated
+		{
+			csc:   &ClientCredentialVariants{PAC: "pac data", ProvisionPAC: true},
+			inner: inner.EapMschapv2,
+			pac:   "pac data",
+		},
+		{
+			csc:   &ClientCredentialVariants{ProvisionPAC: true},
+			inner: inner.EapGtc,
+			err:   "EAP-FAST has no PAC and no server side CA, unauthenticated PAC provisioning is only allowed with EAP-MSCHAPv2",
+		},
+		{
+			csc: &ClientCredentialVariants{ProvisionPAC: true},
+			ssc: &ServerCredentialVariants{CA: []*CertData{{
+				FormatAttr:   "X.509",
+				EncodingAttr: "base64",
+				Value:        "aW52YWxpZA==",
+			}}},
+			inner: inner.EapMschapv2,
+			err:   "invalid server side CA: failed parsing certificate: x509: malformed certificate",
+		},
+	}
+	for _, c := range cases {
+		am := &AuthenticationMethod{
+			EAPMethod:            &EAPMethod{Type: int(method.FAST)},
+			ClientSideCredential: c.csc,
+			ServerSideCredential: c.ssc,
+			InnerAuthenticationMethod: []*InnerAuthenticationMethod{{
+				EAPMethod: &EAPMethod{Type: int(c.inner)},
+			}},
+		}
+		n, err := am.Network(network.Base{})
+		if utilsx.ErrorString(err) != c.err {
+			t.Fatalf("error not equal, want: %v, got: %v", c.err, err)
+		}
+		if err != nil {
+			continue
+		}
+		nt := n.(*network.NonTLS)
+		if nt.PAC != c.pac {
+			t.Fatalf("PAC not equal, want: %v, got: %v", c.pac, nt.PAC)
+		}
+		if nt.ProvisionPAC != c.provision {
+			t.Fatalf("PAC provisioning not equal, want: %v, got: %v", c.provision, nt.ProvisionPAC)
+		}
+		if nt.MethodName() != "fast-mschapv2" {
+			t.Fatalf("method name not equal, want: fast-mschapv2, got: %v", nt.MethodName())
+		}
+	}
+}
+
+func TestTEAP(t *testing.T) {
+	b, err := os.ReadFile(path.Join("test_data", "teap-eap.xml"))
+	if err != nil {
+		t.Fatalf("failed reading file: %v", err)
+	}
+	eipl, err := Parse(b)
+	if err != nil {
+		t.Fatalf("failed parsing file: %v", err)
+	}
+	p := eipl.EAPIdentityProviders[0]
+	ams := p.AuthenticationMethods.AuthenticationMethod
+	if got := method.Type(ams[0].EAPMethod.Type); got != method.TEAP {
+		t.Fatalf("EAP method not equal, want: %v, got: %v", method.TEAP, got)
+	}
+
+	ns, err := p.Networks()
+	if err != nil {
+		t.Fatalf("failed getting networks: %v", err)
+	}
+	var names []string
+	for _, n := range ns {
+		names = append(names, n.MethodName())
+	}
+	want := []string{"teap-mschapv2", "pwd"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("methods not equal, want: %v, got: %v", want, names)
+	}
+	n, ok := ns[0].(*network.NonTLS)
+	if !ok {
+		t.Fatalf("network is not a non TLS network: %T", ns[0])
+	}
+	if n.MethodType != method.TEAP || n.InnerAuth != inner.EapMschapv2 {
+		t.Fatalf("method not equal, want: %v %v, got: %v %v", method.TEAP, inner.EapMschapv2, n.MethodType, n.InnerAuth)
+	}
+	if len(n.Certs) != 1 || !reflect.DeepEqual(n.ServerIDs, []string{"teap.example.org"}) {
+		t.Fatalf("server side credentials not parsed, got certs: %v, server IDs: %v", n.Certs, n.ServerIDs)
+	}
+	if n.Credentials.Suffix != "@teap.example.org" {
+		t.Fatalf("suffix not equal, want: @teap.example.org, got: %v", n.Credentials.Suffix)
+	}
+}
@@ -0,0 +1,166 @@
+package eap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"mime"
+	"strconv"
+	"strings"
+	"unicode"
+
+	// register the decoders for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/geteduroam/linux-app/internal/network"
+)
+
+// MaxLogoSize is the maximum size of a decoded logo in bytes
+const MaxLogoSize = 1 << 20
+
+// MaxLogoDimension is the maximum width and height of a logo in pixels
+const MaxLogoDimension = 2048
+
+// logoFormats are the supported MIME types of the logo with the format name that is returned by image.DecodeConfig
+// SVG is not a format of the image package and is checked separately
+var logoFormats = map[string]string{
+	"image/png":     "png",
+	"image/jpeg":    "jpeg",
+	"image/svg+xml": "svg",
+}
+
+// logoMime normalizes the MIME type of a logo
+// It returns an error if the type is not supported
+func logoMime(attr string) (string, error) {
+	m, _, err := mime.ParseMediaType(attr)
+	if err != nil {
+		return "", fmt.Errorf("invalid logo MIME type %q: %w", attr, err)
+	}
+	// a common mistake
+	if m == "image/jpg" {
+		m = "image/jpeg"
+	}
+	if _, ok := logoFormats[m]; !ok {
+		return "", fmt.Errorf("logo MIME type %q is not supported", attr)
+	}
+	return m, nil
+}
+
+// checkDimensions checks whether the width and height are within the limits
+func checkDimensions(w float64, h float64) error {
+	if w <= 0 || h <= 0 {
+		return fmt.Errorf("logo dimensions %vx%v are invalid", w, h)
+	}
+	if w > MaxLogoDimension || h > MaxLogoDimension {
+		return fmt.Errorf("logo dimensions %vx%v exceed the maximum of %dx%d", w, h, MaxLogoDimension, MaxLogoDimension)
+	}
+	return nil
+}
+
+// svgLength parses an SVG length, e.g. 100 or 100px
+// Relative lengths, e.g. 100%, cannot be determined and return false
+func svgLength(s string) (float64, bool) {
+	v := strings.TrimSuffix(strings.TrimSpace(s), "px")
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
+// checkSVG checks whether the data is an SVG image within the dimension limits
+// The dimensions are taken from the width and height attributes and otherwise from the viewBox
+func checkSVG(data []byte) error {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("logo is not a valid SVG image: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if se.Name.Local != "svg" {
+			return fmt.Errorf("logo root element %q is not svg", se.Name.Local)
+		}
+		var w, h float64
+		var wok, hok bool
+		var viewBox string
+		for _, a := range se.Attr {
+			switch a.Name.Local {
+			case "width":
+				w, wok = svgLength(a.Value)
+			case "height":
+				h, hok = svgLength(a.Value)
+			case "viewBox":
+				viewBox = a.Value
+			}
+		}
+		if !wok || !hok {
+			f := strings.FieldsFunc(viewBox, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+			if len(f) != 4 {
+				return errors.New("logo SVG has no width, height or viewBox")
+			}
+			w, wok = svgLength(f[2])
+			h, hok = svgLength(f[3])
+			if !wok || !hok {
+				return fmt.Errorf("logo SVG has an invalid viewBox %q", viewBox)
+			}
+		}
+		return checkDimensions(w, h)
+	}
+}
+
+// Logo returns the logo for the provider info elements
+// The logo must be a base64 encoded PNG, JPEG or SVG image that is within the size and dimension limits
+// If the logo is an unexpected type or too big, we return an empty logo and an error
+func (pi *ProviderInfoElements) Logo() (network.Logo, error) {
+	if pi.ProviderLogo == nil {
+		return network.Logo{}, errors.New("no provider logo found")
+	}
+	data := *pi.ProviderLogo
+	m, err := logoMime(data.MimeAttr)
+	if err != nil {
+		return network.Logo{}, err
+	}
+	if data.EncodingAttr != "base64" {
+		return network.Logo{}, errors.New("image is not base64")
+	}
+	// the XML value can be wrapped over multiple lines
+	enc := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, data.Value)
+	if base64.StdEncoding.DecodedLen(len(enc)) > MaxLogoSize {
+		return network.Logo{}, fmt.Errorf("logo exceeds the maximum size of %d bytes", MaxLogoSize)
+	}
+	b, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return network.Logo{}, fmt.Errorf("logo is not valid base64: %w", err)
+	}
+	if m == "image/svg+xml" {
+		err = checkSVG(b)
+	} else {
+		var cfg image.Config
+		var format string
+		cfg, format, err = image.DecodeConfig(bytes.NewReader(b))
+		if err == nil && format != logoFormats[m] {
+			err = fmt.Errorf("logo is a %s image but the MIME type is %q", format, m)
+		}
+		if err == nil {
+			err = checkDimensions(float64(cfg.Width), float64(cfg.Height))
+		}
+	}
+	if err != nil {
+		return network.Logo{}, err
+	}
+	return network.Logo{Mime: m, Data: b}, nil
+}
@@ -3,6 +3,9 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"golang.org/x/exp/slog"
@@ -19,6 +22,7 @@ import (
 type Handlers struct {
 	// CredentialsH is the handler for asking for the username and password
 	// c are the credentials which also contains prefixes and suffixes for the username
+	// For a one-time token only the username is asked, the token is asked by NetworkManager when connecting
 	// pi is the provider info
 	// It returns the username and password that were filled in
 	CredentialsH func(c network.Credentials, pi network.ProviderInfo) (string, string, error)
@@ -26,45 +30,319 @@ type Handlers struct {
 	// CertificateH is the handler for asking for the client certificate from the user
 	// It returns the certificate, the passphrase and an error
 	CertificateH func(cert string, passphrase string, pi network.ProviderInfo) (string, string, error)
+
+	// ProviderH is the handler for choosing the identity provider when the EAP config contains multiple
+	// candidates are the networks of the viable identity providers
+	// It returns the index of the chosen network
+	ProviderH func(candidates []network.Network) (int, error)
+
+	// MediumH is the handler for choosing whether to install the wireless networks, the wired networks or both
+	// It is only called when the network can be installed on both
+	MediumH func(pi network.ProviderInfo) (network.Medium, error)
+
+	// MethodH is the handler for choosing the authentication method when the identity provider offers multiple viable ones
+	// candidates are the networks of the authentication methods in order of preference
+	// It returns the index of the chosen network
+	MethodH func(candidates []network.Network) (int, error)
+
+	// Method is the name of the authentication method that is chosen instead of asking, e.g. ttls-pap or peap
+	// This is matched against the method with and without the inner authentication method, see network.Network.MethodName
+	Method string
+
+	// PMF overrides the protected management frames policy of the SSIDs that is derived from the EAP config
+	// PMFDefault keeps the derived policy, see PMFPolicy
+	PMF network.PMF
+
+	// WiredH is the handler for telling the user which wired connections are added
+	// These are not activated automatically, so the user has to activate them on a wired network of the organization
+	// It is only called when wired connections are added and can be nil
+	WiredH func(ids []string)
+}
+
+// Position gets the position of the user that is used to find the nearest locations
+// The override, e.g. from a --location flag, is preferred over the location in the config
+// It returns nil if no position is known
+func Position(override string) (*network.Location, error) {
+	if override != "" {
+		l, err := network.ParseLocation(override)
+		if err != nil {
+			return nil, err
+		}
+		return &l, nil
+	}
+	c, err := config.Load()
+	if err != nil || c == nil || c.Location == "" {
+		return nil, nil
+	}
+	l, err := network.ParseLocation(c.Location)
+	if err != nil {
+		slog.Debug("Ignoring invalid location in the config", "error", err)
+		return nil, nil
+	}
+	return &l, nil
+}
+
+// PMFPolicy gets the protected management frames policy that overrides the policy derived from the EAP config
+// The override, e.g. from a --pmf flag, is preferred over the policy in the config
+// It returns PMFDefault if no policy is set
+func PMFPolicy(override string) (network.PMF, error) {
+	if override != "" {
+		return network.ParsePMF(override)
+	}
+	c, err := config.Load()
+	if err != nil || c == nil || c.PMF == "" {
+		return network.PMFDefault, nil
+	}
+	p, err := network.ParsePMF(c.PMF)
+	if err != nil {
+		slog.Debug("Ignoring invalid PMF policy in the config", "error", err)
+		return network.PMFDefault, nil
+	}
+	return p, nil
 }
 
 // network gets the network by parsing the connection using the EAP byte array
-func (h Handlers) network(config []byte) (network.Network, error) {
+// It also returns the networks of the other viable authentication methods in order of preference, these are installed as fallbacks
+func (h Handlers) network(config []byte) (network.Network, []network.Network, error) {
 	// First we parse the config
 	unpack, err := eap.Parse(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	n, err := unpack.Network()
+	ns, err := unpack.Networks()
 	if err != nil {
+		return nil, nil, err
+	}
+	ns, err = supported(ns)
+	if err != nil {
+		return nil, nil, err
+	}
+	methods := ns[0]
+	if len(ns) > 1 && h.ProviderH != nil {
+		// the providers are shown with their preferred method
+		candidates := make([]network.Network, len(ns))
+		for i, n := range ns {
+			candidates[i] = n[0]
+		}
+		idx, err := h.ProviderH(candidates)
+		if err != nil {
+			slog.Debug("Error asking for the identity provider", "error", err)
+			return nil, nil, err
+		}
+		if idx < 0 || idx >= len(ns) {
+			return nil, nil, fmt.Errorf("invalid identity provider choice: %d", idx)
+		}
+		methods = ns[idx]
+	}
+	n, err := h.method(methods)
+	if err != nil {
+		return nil, nil, err
+	}
+	var fallbacks []network.Network
+	for _, m := range methods {
+		if m != n {
+			fallbacks = append(fallbacks, m)
+		}
+	}
+	return n, fallbacks, nil
+}
+
+// supported removes the networks with authentication methods that NetworkManager cannot connect with, see nm.SupportsMethod
+// The identity providers without supported networks are removed
+// It returns an error with the reasons if no network is left
+func supported(ns [][]network.Network) ([][]network.Network, error) {
+	var got [][]network.Network
+	var rejected []string
+	for _, methods := range ns {
+		var ok []network.Network
+		for _, n := range methods {
+			if err := nm.SupportsMethod(n.Method()); err != nil {
+				slog.Warn("Skipping authentication method that is not supported", "method", n.MethodName(), "reason", err)
+				rejected = append(rejected, fmt.Sprintf("%s: %v", n.MethodName(), err))
+				continue
+			}
+			ok = append(ok, n)
+		}
+		if len(ok) > 0 {
+			got = append(got, ok)
+		}
+	}
+	if len(got) == 0 {
+		return nil, fmt.Errorf("no authentication method of the profile is supported by NetworkManager: %s", strings.Join(rejected, "; "))
+	}
+	return got, nil
+}
+
+// method chooses the network with the authentication method that is used
+// The methods are in order of preference
+func (h Handlers) method(methods []network.Network) (network.Network, error) {
+	if h.Method != "" {
+		var names []string
+		for _, n := range methods {
+			if n.MethodName() == h.Method || n.Method().String() == h.Method {
+				return n, nil
+			}
+			names = append(names, n.MethodName())
+		}
+		return nil, fmt.Errorf("the authentication method %q is not offered by the profile, available methods: %s", h.Method, strings.Join(names, ", "))
+	}
+	if len(methods) == 1 || h.MethodH == nil {
+		return methods[0], nil
+	}
+	idx, err := h.MethodH(methods)
+	if err != nil {
+		slog.Debug("Error asking for the authentication method", "error", err)
 		return nil, err
 	}
+	if idx < 0 || idx >= len(methods) {
+		return nil, fmt.Errorf("invalid authentication method choice: %d", idx)
+	}
+	return methods[idx], nil
+}
 
-	return n, nil
+// base returns the base of the network
+func base(n network.Network) *network.Base {
+	switch t := n.(type) {
+	case *network.NonTLS:
+		return &t.Base
+	case *network.TLS:
+		return &t.Base
+	}
+	return nil
+}
+
+// fallbacks prepares the fallback networks such that they can be installed next to the chosen network `n`
+// The fallbacks use the same medium and PMF policy as the chosen network
+// Their credentials or client certificate are taken from the chosen network as we do not want to ask the user again,
+// fallbacks for which that is not possible are skipped
+func (h Handlers) fallbacks(n network.Network, candidates []network.Network, medium network.Medium) []network.Network {
+	var fbs []network.Network
+	for _, f := range candidates {
+		switch t := f.(type) {
+		case *network.NonTLS:
+			if t.InnerAuth.NeedsCertificate() {
+				if t.InnerCert == nil {
+					slog.Info("Not installing the fallback authentication method as it needs a client certificate", "method", f.MethodName())
+					continue
+				}
+				break
+			}
+			c, ok := n.(*network.NonTLS)
+			// the username of inner EAP-TLS is from the client certificate
+			canCopy := ok && !c.InnerAuth.NeedsCertificate()
+			if t.Credentials.Username == "" {
+				if !canCopy {
+					slog.Info("Not installing the fallback authentication method as it needs credentials", "method", f.MethodName())
+					continue
+				}
+				t.Credentials.Username = c.Credentials.Username
+			}
+			// a one-time token is not saved, NetworkManager asks for it when connecting
+			if t.Credentials.OneTimeToken || t.Credentials.Password != "" {
+				break
+			}
+			// a one-time token is not a password
+			if !canCopy || c.Credentials.OneTimeToken {
+				slog.Info("Not installing the fallback authentication method as it needs a password", "method", f.MethodName())
+				continue
+			}
+			t.Credentials.Password = c.Credentials.Password
+		case *network.TLS:
+			if t.ClientCert == nil {
+				c, ok := n.(*network.TLS)
+				if !ok {
+					slog.Info("Not installing the fallback authentication method as it needs a client certificate", "method", f.MethodName())
+					continue
+				}
+				t.ClientCert = c.ClientCert
+			}
+		default:
+			continue
+		}
+		b := base(f)
+		b.Restrict(medium)
+		if h.PMF != network.PMFDefault {
+			b.SetPMF(h.PMF)
+		}
+		fbs = append(fbs, f)
+	}
+	return fbs
+}
+
+// clientCert asks for the client certificate and its passphrase using the certificate handler
+// raw is the PKCS12 container from the EAP config, if any, and passphrase its passphrase
+func (h Handlers) clientCert(raw string, passphrase string, pi network.ProviderInfo) (*cert.ClientCert, error) {
+	// if a PKCS12 file is uploaded by the user we expect it to be not base64 encoded
+	b64 := raw != ""
+	// TODO: Loop until the PKCS12 can be decrypted successfully?
+	ccert, passphrase, err := h.CertificateH(raw, passphrase, pi)
+	if err != nil {
+		return nil, err
+	}
+	return cert.NewClientCert(ccert, passphrase, b64)
 }
 
 // Configure configures the connection using the parsed configuration
 // It installs it using NetworkManager
 func (h Handlers) Configure(eap []byte) (*time.Time, *time.Time, error) {
 	// Get the network
-	n, err := h.network(eap)
+	n, candidates, err := h.network(eap)
 	if err != nil {
 		return nil, nil, err
 	}
+	// an expired profile would not work anymore
+	if exp := n.Expiry(); exp != nil && !exp.After(time.Now()) {
+		return nil, nil, fmt.Errorf("the EAP metadata expired on %s, please get new metadata from your organization", exp.Local().Format(time.DateTime))
+	}
+	medium := n.Media()
+	if medium == network.MediumBoth && h.MediumH != nil {
+		medium, err = h.MediumH(n.ProviderInfo())
+		if err != nil {
+			slog.Debug("Error asking for the medium", "error", err)
+			return nil, nil, err
+		}
+	}
 	var uuids []string
 
 	// get the previous UUID if the config can be loaded
+	// the other settings in the config are kept when writing it back
+	var nc config.Config
 	c, err := config.Load()
-	if err == nil {
+	if err == nil && c != nil {
 		uuids = c.UUIDs
+		nc = *c
 	}
 
-	var validFor *time.Time
+	// the validity is the ValidUntil of the EAP config, for TLS the client certificate can expire earlier
+	validFor := n.Expiry()
 	var validAt *time.Time
 	switch t := n.(type) {
 	case *network.NonTLS:
-		if t.Credentials.Username == "" || t.Credentials.Password == "" {
+		t.Restrict(medium)
+		if h.PMF != network.PMFDefault {
+			t.SetPMF(h.PMF)
+		}
+		// TTLS with inner EAP-TLS needs a client certificate instead of a password
+		if t.InnerAuth.NeedsCertificate() {
+			if t.InnerCert == nil {
+				t.InnerCert, err = h.clientCert(t.RawInnerPKCS12, t.InnerPassphrase, n.ProviderInfo())
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			if t.Credentials.Username == "" {
+				t.Credentials.Username = t.InnerCert.SubjectCN()
+			}
+			vBeg, vEnd := t.InnerCert.Validity()
+			if validFor == nil || vEnd.Before(*validFor) {
+				validFor = &vEnd
+			}
+			validAt = &vBeg
+			break
+		}
+		// a one-time token is asked by NetworkManager when connecting, see nm.Install
+		if t.Credentials.Username == "" || (t.Credentials.Password == "" && !t.Credentials.OneTimeToken) {
 			username, password, cerr := h.CredentialsH(t.Credentials, n.ProviderInfo())
 			if cerr != nil {
 				slog.Debug("Error asking for credentials", "error", err)
@@ -73,41 +351,55 @@ func (h Handlers) Configure(eap []byte) (*time.Time, *time.Time, error) {
 			t.Credentials.Username = username
 			t.Credentials.Password = password
 		}
-		uuids, err = nm.Install(*t, uuids)
 	case *network.TLS:
-		// if a PKCS12 file is uploaded by the user we expect it to be not base64 encoded
-		b64 := t.RawPKCS12 != ""
-		// TODO: Loop until the PKCS12 can be decrypted successfully?
+		t.Restrict(medium)
+		if h.PMF != network.PMFDefault {
+			t.SetPMF(h.PMF)
+		}
 		if t.ClientCert == nil {
-			ccert, passphrase, err := h.CertificateH(t.RawPKCS12, t.Password, n.ProviderInfo())
-			if err != nil {
-				return nil, nil, err
-			}
-			// here the data is not base64 encoded
-			t.ClientCert, err = cert.NewClientCert(ccert, passphrase, b64)
+			t.ClientCert, err = h.clientCert(t.RawPKCS12, t.Password, n.ProviderInfo())
 			if err != nil {
 				return nil, nil, err
 			}
 		}
 		vBeg, vEnd := t.Validity()
-		validFor = &vEnd
+		if validFor == nil || vEnd.Before(*validFor) {
+			validFor = &vEnd
+		}
 		validAt = &vBeg
-		uuids, err = nm.InstallTLS(*t, uuids)
 	default:
 		panic("unsupported network")
 	}
+	// the other authentication methods are installed with a lower priority
+	ns := append([]network.Network{n}, h.fallbacks(n, candidates, medium)...)
+	uuids, err = nm.Install(ns, uuids)
 	if err != nil {
-		if len(uuids) == 0 {
+		// the fallbacks are not what the user chose, so only their failure is not fatal
+		if len(uuids) == 0 || errors.Is(err, nm.ErrPreferred) {
 			slog.Error("Error installing network", "error", err)
+			// remember the connections that were installed such that they are updated or removed the next time
+			if len(uuids) > 0 {
+				nc.UUIDs = uuids
+				if werr := nc.Write(); werr != nil {
+					slog.Debug("Error writing the config", "error", werr)
+				}
+			}
 			return nil, nil, err
 		}
-		slog.Info("One of the networks failed to install", "error", err)
+		slog.Warn("Some of the fallback networks failed to install", "error", err)
 	}
-	// save the config with the uuid
-	nc := config.Config{
-		UUIDs:    uuids,
-		Validity: validFor,
+	// the wired networks are removed if the medium does not allow them, see network.Base.Restrict
+	if b := base(n); h.WiredH != nil && len(b.Wired) > 0 {
+		h.WiredH(nm.WiredIDs(*b))
 	}
+	// the profile is valid from now on if only the EAP config expires
+	if validFor != nil && validAt == nil {
+		now := time.Now()
+		validAt = &now
+	}
+	// save the config with the uuid
+	nc.UUIDs = uuids
+	nc.Validity = validFor
 	err = nc.Write()
 	if err != nil {
 		slog.Debug("Error configuring network", "error", err)
@@ -0,0 +1,12 @@
+// Package httpclient implements the HTTP client that is shared for discovery and profile requests
+package httpclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Client is the shared HTTP client that is used for the discovery and profile requests
+// It can be replaced by callers and tests, e.g. to set a proxy aware transport
+// Note that requests should be cancelled and given deadlines using their context
+var Client = &http.Client{Timeout: 10 * time.Second}
@@ -0,0 +1,162 @@
+// Package lang implements the languages of the user that are used to pick localized values
+package lang
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/exp/slog"
+	"golang.org/x/text/language"
+
+	"github.com/geteduroam/linux-app/internal/config"
+)
+
+// system is the list of languages of the user in order of preference
+var system = []language.Tag{language.English}
+
+// envVars are the environment variables that contain the locale of the user in order of precedence
+// LANGUAGE is a colon separated list of fallback languages
+var envVars = []string{"LC_ALL", "LC_MESSAGES", "LANG", "LANGUAGE"}
+
+// System returns the most preferred language of the user
+func System() language.Tag {
+	return system[0]
+}
+
+// Preferred returns the languages of the user in order of preference
+func Preferred() []language.Tag {
+	return system
+}
+
+// Set sets the languages of the user in order of preference
+// If no languages are given, English is used
+func Set(tags ...language.Tag) {
+	if len(tags) == 0 {
+		system = []language.Tag{language.English}
+		return
+	}
+	system = tags
+}
+
+// Parse parses a POSIX locale, e.g. nl_NL.UTF-8, or a BCP 47 language tag, e.g. nl-NL
+func Parse(locale string) (language.Tag, error) {
+	l := strings.TrimSpace(locale)
+	// remove the codeset and modifier, e.g. .UTF-8 and @euro
+	if i := strings.IndexAny(l, ".@"); i >= 0 {
+		l = l[:i]
+	}
+	// C and POSIX mean no localization, these are not languages
+	if l == "" || l == "C" || l == "POSIX" {
+		return language.Und, fmt.Errorf("locale %q is not a language", locale)
+	}
+	tag, err := language.Parse(l)
+	if err != nil {
+		return language.Und, fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+	return tag, nil
+}
+
+// parseList parses a list of locales separated by a colon or a comma
+// It returns the languages that could be parsed and the error of the first one that could not
+func parseList(list string) ([]language.Tag, error) {
+	var tags []language.Tag
+	var first error
+	for _, l := range strings.FieldsFunc(list, func(r rune) bool { return r == ':' || r == ',' }) {
+		tag, err := Parse(l)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, first
+}
+
+// fromEnv returns the languages from the environment in order of precedence
+func fromEnv() []language.Tag {
+	var tags []language.Tag
+	for _, v := range envVars {
+		val := os.Getenv(v)
+		if val == "" {
+			continue
+		}
+		got, err := parseList(val)
+		if err != nil {
+			slog.Debug("Ignoring invalid locale in the environment", "variable", v, "error", err)
+		}
+		tags = append(tags, got...)
+	}
+	return tags
+}
+
+// dedup removes duplicate languages while keeping the order
+func dedup(tags []language.Tag) []language.Tag {
+	var r []language.Tag
+	seen := make(map[language.Tag]bool)
+	for _, t := range tags {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		r = append(r, t)
+	}
+	return r
+}
+
+// Configure sets the languages of the user
+// The override, e.g. from a --lang flag, is preferred over the language in the config
+// which is preferred over the environment.
+// The environment languages are kept as fallbacks
+func Configure(override string) error {
+	var tags []language.Tag
+	if override != "" {
+		got, err := parseList(override)
+		if err != nil {
+			return err
+		}
+		tags = append(tags, got...)
+	} else if cfg, err := config.Load(); err == nil && cfg != nil && cfg.Language != "" {
+		got, err := parseList(cfg.Language)
+		if err != nil {
+			slog.Debug("Ignoring invalid language in the config", "error", err)
+		}
+		tags = append(tags, got...)
+	}
+	Set(dedup(append(tags, fromEnv()...))...)
+	return nil
+}
+
+// Best returns the index of the language tag that best matches the languages of the user
+// Empty and invalid tags are skipped, if no tag matches false is returned
+func Best(tags []string) (int, bool) {
+	var supported []language.Tag
+	var indices []int
+	for i, t := range tags {
+		if t == "" {
+			continue
+		}
+		p, err := language.Parse(t)
+		// tag is invalid, just continue with the next option
+		if err != nil {
+			continue
+		}
+		supported = append(supported, p)
+		indices = append(indices, i)
+	}
+	if len(supported) == 0 {
+		return 0, false
+	}
+	m := language.NewMatcher(supported)
+	_, idx, conf := m.Match(system...)
+	if conf == language.No {
+		return 0, false
+	}
+	return indices[idx], true
+}
+
+func init() {
+	Set(dedup(fromEnv())...)
+}
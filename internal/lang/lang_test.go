@@ -0,0 +1,132 @@
+package lang
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/geteduroam/linux-app/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    language.Tag
+		wanterr bool
+	}{
+		{input: "nl_NL.UTF-8", want: language.MustParse("nl-NL")},
+		{input: "de_DE@euro", want: language.MustParse("de-DE")},
+		{input: "en-GB", want: language.BritishEnglish},
+		{input: "nl", want: language.Dutch},
+		{input: "C", wanterr: true},
+		{input: "POSIX", wanterr: true},
+		{input: "", wanterr: true},
+		{input: "not a language", wanterr: true},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.input)
+		if (err != nil) != c.wanterr {
+			t.Fatalf("error for: %q not as expected, want error: %v, got: %v", c.input, c.wanterr, err)
+		}
+		if err == nil && got != c.want {
+			t.Fatalf("language for: %q not equal, want: %v, got: %v", c.input, c.want, got)
+		}
+	}
+}
+
+func mockEnv(t *testing.T, env map[string]string) {
+	for _, v := range envVars {
+		t.Setenv(v, env[v])
+	}
+	// no config
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+}
+
+func TestConfigure(t *testing.T) {
+	cases := []struct {
+		env      map[string]string
+		override string
+		config   string
+		want     []language.Tag
+		wanterr  bool
+	}{
+		{
+			env:  map[string]string{},
+			want: []language.Tag{language.English},
+		},
+		{
+			env:  map[string]string{"LANG": "nl_NL.UTF-8", "LC_ALL": "de_DE.UTF-8"},
+			want: []language.Tag{language.MustParse("de-DE"), language.MustParse("nl-NL")},
+		},
+		{
+			env:  map[string]string{"LANG": "C", "LC_MESSAGES": "fr_FR", "LANGUAGE": "nl:en"},
+			want: []language.Tag{language.MustParse("fr-FR"), language.Dutch, language.English},
+		},
+		{
+			env:      map[string]string{"LANG": "nl_NL.UTF-8"},
+			override: "de,en",
+			want:     []language.Tag{language.German, language.English, language.MustParse("nl-NL")},
+		},
+		{
+			env:    map[string]string{"LANG": "nl_NL.UTF-8"},
+			config: "de",
+			want:   []language.Tag{language.German, language.MustParse("nl-NL")},
+		},
+		{
+			env:      map[string]string{"LANG": "nl_NL.UTF-8"},
+			override: "en",
+			config:   "de",
+			want:     []language.Tag{language.English, language.MustParse("nl-NL")},
+		},
+		{
+			env:      map[string]string{"LANG": "nl_NL.UTF-8"},
+			override: "C",
+			wanterr:  true,
+		},
+	}
+	prev := Preferred()
+	defer Set(prev...)
+	for _, c := range cases {
+		mockEnv(t, c.env)
+		if c.config != "" {
+			if err := (config.Config{Language: c.config}).Write(); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+		}
+		err := Configure(c.override)
+		if (err != nil) != c.wanterr {
+			t.Fatalf("error not as expected, want error: %v, got: %v", c.wanterr, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(Preferred(), c.want) {
+			t.Fatalf("languages not equal, want: %v, got: %v", c.want, Preferred())
+		}
+	}
+}
+
+func TestBest(t *testing.T) {
+	cases := []struct {
+		tags   []string
+		system []language.Tag
+		want   int
+		wantok bool
+	}{
+		{tags: []string{"en", "nl"}, system: []language.Tag{language.Dutch}, want: 1, wantok: true},
+		{tags: []string{"", "invalid tag", "nl"}, system: []language.Tag{language.Dutch}, want: 2, wantok: true},
+		{tags: []string{"en", "nl"}, system: []language.Tag{language.German, language.Dutch}, want: 1, wantok: true},
+		{tags: []string{"en", "de"}, system: []language.Tag{language.Dutch}, want: 0, wantok: false},
+		{tags: []string{"", "any"}, system: []language.Tag{language.Dutch}, want: 0, wantok: false},
+	}
+	prev := Preferred()
+	defer Set(prev...)
+	for _, c := range cases {
+		Set(c.system...)
+		got, ok := Best(c.tags)
+		if got != c.want || ok != c.wantok {
+			t.Fatalf("best for: %v not equal, want: %v, %v, got: %v, %v", c.tags, c.want, c.wantok, got, ok)
+		}
+	}
+}
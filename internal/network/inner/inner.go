@@ -18,6 +18,10 @@ const (
 	Mschap Type = 2
 	// Mschapv2 is MSCHAPv2 inner authentication
 	Mschapv2 Type = 3
+	// EapGtc is EAP-GTC inner authentication, used for one-time tokens
+	EapGtc Type = 6
+	// EapTLS is EAP-TLS inner authentication with a client certificate
+	EapTLS Type = 13
 	// TODO: remove this? https://github.com/geteduroam/windows-app/blob/f11f00dee3eb71abd38537e18881463f83b180d3/CHANGELOG.md?plain=1#L34
 	// EapPeapMschapv2 is EAP-PEAP-MSCHAPv2 inner authentication
 	EapPeapMschapv2 Type = 25
@@ -32,10 +36,24 @@ func (t Type) EAP() bool {
 		return true
 	case EapMschapv2:
 		return true
+	case EapGtc:
+		return true
+	case EapTLS:
+		return true
 	}
 	return false
 }
 
+// NeedsToken returns whether or not the password for this inner type is a one-time token
+func (t Type) NeedsToken() bool {
+	return t == EapGtc
+}
+
+// NeedsCertificate returns whether or not this inner type needs a client certificate instead of a password
+func (t Type) NeedsCertificate() bool {
+	return t == EapTLS
+}
+
 // String returns the string representation of the inner type
 func (t Type) String() string {
 	switch t {
@@ -49,6 +67,10 @@ func (t Type) String() string {
 		fallthrough
 	case EapMschapv2:
 		return "mschapv2"
+	case EapGtc:
+		return "gtc"
+	case EapTLS:
+		return "tls"
 	}
 	return ""
 }
@@ -66,7 +88,7 @@ func IsValid(mt method.Type, input int, eap bool) bool {
 	if Type(input).EAP() != eap {
 		return false
 	}
-	// For TTLS, we support PAP, MSCHAP, MSCHAPv2 and EAP MSCHAPV2
+	// For TTLS, we support PAP, MSCHAP, MSCHAPv2, EAP MSCHAPV2, EAP GTC and EAP TLS
 	if mt == method.TTLS {
 		switch Type(input) {
 		case Pap:
@@ -77,16 +99,22 @@ func IsValid(mt method.Type, input int, eap bool) bool {
 			return true
 		case EapMschapv2:
 			return true
+		case EapGtc:
+			return true
+		case EapTLS:
+			return true
 		}
 		return false
 	}
-	// for PEAP, we only support EAP*MSCHAPV2
-	if mt == method.PEAP {
+	// for PEAP, FAST and TEAP, we support EAP*MSCHAPV2 and EAP GTC
+	if mt == method.PEAP || mt == method.FAST || mt == method.TEAP {
 		switch Type(input) {
 		case EapPeapMschapv2:
 			return true
 		case EapMschapv2:
 			return true
+		case EapGtc:
+			return true
 		}
 		return false
 	}
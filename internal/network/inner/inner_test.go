@@ -89,6 +89,48 @@ func TestIsValid(t *testing.T) {
 			eap:   false, // EAP not matching
 			want:  false,
 		},
+		{
+			mt:    method.TTLS,
+			input: 6, // 6: EAP_GTC
+			eap:   true,
+			want:  true,
+		},
+		{
+			mt:    method.TTLS,
+			input: 13, // 13: EAP_TLS
+			eap:   true,
+			want:  true,
+		},
+		{
+			mt:    method.PEAP,
+			input: 6, // 6: EAP_GTC
+			eap:   true,
+			want:  true,
+		},
+		{
+			mt:    method.PEAP,
+			input: 13, // 13: EAP_TLS, not supported with PEAP
+			eap:   true,
+			want:  false,
+		},
+		{
+			mt:    method.FAST,
+			input: 26, // 26: EAP_MSCHAPV2
+			eap:   true,
+			want:  true,
+		},
+		{
+			mt:    method.FAST,
+			input: 3,     // 3: MSCHAPV2
+			eap:   false, // FAST only tunnels EAP methods
+			want:  false,
+		},
+		{
+			mt:    method.TEAP,
+			input: 6, // 6: EAP_GTC
+			eap:   true,
+			want:  true,
+		},
 	}
 
 	for _, c := range cases {
@@ -0,0 +1,78 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// earthRadius is the mean radius of the earth in kilometers
+const earthRadius = 6371.0
+
+// Location is a geographic location, e.g. of a campus
+type Location struct {
+	// Latitude is the latitude in degrees
+	Latitude float64
+	// Longitude is the longitude in degrees
+	Longitude float64
+}
+
+// NewLocation creates a location and validates the latitude and longitude
+func NewLocation(lat float64, lon float64) (Location, error) {
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return Location{}, fmt.Errorf("latitude %v is not between -90 and 90", lat)
+	}
+	if math.IsNaN(lon) || lon < -180 || lon > 180 {
+		return Location{}, fmt.Errorf("longitude %v is not between -180 and 180", lon)
+	}
+	return Location{Latitude: lat, Longitude: lon}, nil
+}
+
+// ParseLocation parses a location from a string in the format "latitude,longitude", e.g. "52.0,4.36"
+func ParseLocation(s string) (Location, error) {
+	lats, lons, ok := strings.Cut(s, ",")
+	if !ok {
+		return Location{}, errors.New("location is not in the format: latitude,longitude")
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(lats), 64)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid latitude: %w", err)
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(lons), 64)
+	if err != nil {
+		return Location{}, fmt.Errorf("invalid longitude: %w", err)
+	}
+	return NewLocation(lat, lon)
+}
+
+// String returns the location as "latitude, longitude"
+func (l Location) String() string {
+	return fmt.Sprintf("%.4f, %.4f", l.Latitude, l.Longitude)
+}
+
+// Distance returns the great-circle distance in kilometers to another location
+// This uses the haversine formula
+func (l Location) Distance(to Location) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dlat := rad(to.Latitude - l.Latitude)
+	dlon := rad(to.Longitude - l.Longitude)
+	a := math.Sin(dlat/2)*math.Sin(dlat/2) +
+		math.Cos(rad(l.Latitude))*math.Cos(rad(to.Latitude))*math.Sin(dlon/2)*math.Sin(dlon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Nearest returns the location of the provider that is nearest to the given location and the distance in kilometers
+// It returns false if the provider has no locations
+func (pi ProviderInfo) Nearest(from Location) (Location, float64, bool) {
+	var nearest Location
+	dist := math.Inf(1)
+	for _, l := range pi.Locations {
+		if d := from.Distance(l); d < dist {
+			nearest = l
+			dist = d
+		}
+	}
+	return nearest, dist, len(pi.Locations) > 0
+}
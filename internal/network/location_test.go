@@ -0,0 +1,53 @@
+package network
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseLocation(t *testing.T) {
+	cases := []struct {
+		input   string
+		want    Location
+		wanterr bool
+	}{
+		{input: "52.0,4.36", want: Location{Latitude: 52.0, Longitude: 4.36}},
+		{input: " -33.9 , 151.2 ", want: Location{Latitude: -33.9, Longitude: 151.2}},
+		{input: "52.0", wanterr: true},
+		{input: "north,4.36", wanterr: true},
+		{input: "91,0", wanterr: true},
+		{input: "0,-181", wanterr: true},
+	}
+	for _, c := range cases {
+		got, err := ParseLocation(c.input)
+		if (err != nil) != c.wanterr {
+			t.Fatalf("error for: %q not as expected, want error: %v, got: %v", c.input, c.wanterr, err)
+		}
+		if got != c.want {
+			t.Fatalf("location for: %q not equal, want: %v, got: %v", c.input, c.want, got)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	delft := Location{Latitude: 52.0022, Longitude: 4.3736}
+	amsterdam := Location{Latitude: 52.3676, Longitude: 4.9041}
+	groningen := Location{Latitude: 53.2194, Longitude: 6.5665}
+
+	// Delft to Amsterdam is about 55 km
+	if d := delft.Distance(amsterdam); math.Abs(d-55) > 2 {
+		t.Fatalf("distance not as expected, want: about 55, got: %v", d)
+	}
+	if d := delft.Distance(delft); d != 0 {
+		t.Fatalf("distance to itself is not zero, got: %v", d)
+	}
+
+	pi := ProviderInfo{Locations: []Location{groningen, amsterdam}}
+	got, _, ok := pi.Nearest(delft)
+	if !ok || got != amsterdam {
+		t.Fatalf("nearest not equal, want: %v, got: %v, %v", amsterdam, got, ok)
+	}
+	if _, _, ok := (ProviderInfo{}).Nearest(delft); ok {
+		t.Fatalf("nearest found for provider without locations")
+	}
+}
@@ -11,6 +11,12 @@ const (
 	TTLS Type = 21
 	// PEAP is the PEAP EAP Method
 	PEAP Type = 25
+	// FAST is the EAP-FAST EAP Method
+	FAST Type = 43
+	// PWD is the EAP-PWD EAP Method
+	PWD Type = 52
+	// TEAP is the EAP-TEAP EAP Method
+	TEAP Type = 55
 )
 
 // IsValid returns whether or not an integer is a valid method type
@@ -22,6 +28,12 @@ func IsValid(input int) bool {
 		return true
 	case PEAP:
 		return true
+	case FAST:
+		return true
+	case PWD:
+		return true
+	case TEAP:
+		return true
 	}
 	return false
 }
@@ -34,6 +46,12 @@ func (m Type) String() string {
 		return "ttls"
 	case PEAP:
 		return "peap"
+	case FAST:
+		return "fast"
+	case PWD:
+		return "pwd"
+	case TEAP:
+		return "teap"
 	}
 	return ""
 }
@@ -48,3 +66,19 @@ func (m Type) NeedsCredentials() bool {
 func (m Type) NeedsCertificate() bool {
 	return m == TLS
 }
+
+// NeedsServerCA returns whether or not this EAP method needs CA certificates to verify the server
+// EAP-PWD does not use certificates, the server is authenticated with the password
+// EAP-FAST can authenticate the server with the PAC, the CA certificates are optional
+func (m Type) NeedsServerCA() bool {
+	return m != PWD && m != FAST
+}
+
+// HasInner returns whether or not this EAP method tunnels an inner authentication method
+func (m Type) HasInner() bool {
+	switch m {
+	case TTLS, PEAP, FAST, TEAP:
+		return true
+	}
+	return false
+}
@@ -27,6 +27,18 @@ func TestIsValid(t *testing.T) {
 			input: 25,
 			want:  true,
 		},
+		{
+			input: 43,
+			want:  true,
+		},
+		{
+			input: 52,
+			want:  true,
+		},
+		{
+			input: 55,
+			want:  true,
+		},
 		{
 			input: -13,
 			want:  false,
@@ -2,6 +2,7 @@
 package network
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/geteduroam/linux-app/internal/network/cert"
@@ -14,8 +15,15 @@ import (
 type Network interface {
 	// Method returns the EAP method
 	Method() method.Type
+	// MethodName returns the name of the EAP method including the inner authentication method, e.g. ttls-pap or tls
+	MethodName() string
 	// ProviderInfo returns the EAP ProviderInfo
 	ProviderInfo() ProviderInfo
+	// Expiry returns the time after which the EAP config should not be used anymore
+	// It returns nil if the EAP config does not expire
+	Expiry() *time.Time
+	// Media returns the media on which the network can be installed
+	Media() Medium
 }
 
 // Help is the struct that contains information on how to contact an organization
@@ -28,6 +36,14 @@ type Help struct {
 	Web string
 }
 
+// Logo is the logo of an organization
+type Logo struct {
+	// Mime is the MIME type of the image, e.g. image/png
+	Mime string
+	// Data is the decoded image
+	Data []byte
+}
+
 // ProviderInfo is the ProviderInfo element for the network
 type ProviderInfo struct {
 	// Helpdesk contains the help information on how to contact the organization that owns the network
@@ -36,18 +52,105 @@ type ProviderInfo struct {
 	Name string
 	// Description is the description of the network as provided by the organization
 	Description string
-	// Logo is the logo of the network, probably the logo of the organization in base64
-	Logo string
+	// Logo is the logo of the network, probably the logo of the organization
+	Logo Logo
 	// Terms is the terms of use for this network
 	Terms string
+	// Locations are the locations of the organization, e.g. the campuses
+	Locations []Location
 }
 
 // SSID is the pair of value and min RSN proto
 type SSID struct {
 	// Value is the SSID
 	Value string
-	// MinRSN is the minimum RSN proto
+	// MinRSN is the minimum RSN proto, CCMP, GCMP or GCMP-256
 	MinRSN string
+	// PMF is the protected management frames policy
+	PMF PMF
+	// SuiteB192 is whether the network uses the WPA3-Enterprise 192-bit mode
+	SuiteB192 bool
+}
+
+// PMF is the policy for protected management frames (802.11w)
+// The values are the same as the pmf values of NetworkManager
+type PMF int8
+
+const (
+	// PMFDefault uses the default policy of the system
+	PMFDefault PMF = iota
+	// PMFDisable disables protected management frames
+	PMFDisable
+	// PMFOptional uses protected management frames if the network supports it, e.g. for WPA3-Enterprise transition mode
+	PMFOptional
+	// PMFRequired only connects if the network supports protected management frames, e.g. for WPA3-Enterprise only mode
+	PMFRequired
+)
+
+// String returns the string representation of the PMF policy
+func (p PMF) String() string {
+	switch p {
+	case PMFDefault:
+		return "default"
+	case PMFDisable:
+		return "disable"
+	case PMFOptional:
+		return "optional"
+	case PMFRequired:
+		return "required"
+	}
+	return ""
+}
+
+// ParsePMF parses a PMF policy from its string representation
+func ParsePMF(s string) (PMF, error) {
+	for _, p := range []PMF{PMFDefault, PMFDisable, PMFOptional, PMFRequired} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return PMFDefault, fmt.Errorf("invalid PMF policy %q, must be default, disable, optional or required", s)
+}
+
+// Wired is a wired IEEE 802.1X network
+type Wired struct {
+	// NetworkID is the identifier of the wired network, this can be empty
+	NetworkID string
+}
+
+// Medium is a set of media on which the network is installed
+type Medium int8
+
+const (
+	// MediumWireless is the medium for the Wi-Fi networks, the SSIDs
+	MediumWireless Medium = 1 << iota
+	// MediumWired is the medium for the wired 802.1X networks
+	MediumWired
+	// MediumBoth is the medium for both wireless and wired networks
+	MediumBoth = MediumWireless | MediumWired
+)
+
+// String returns the string representation of the medium
+func (m Medium) String() string {
+	switch m {
+	case MediumWireless:
+		return "wireless"
+	case MediumWired:
+		return "wired"
+	case MediumBoth:
+		return "both"
+	}
+	return ""
+}
+
+// ParseMedium parses a medium from its string representation
+func ParseMedium(s string) (Medium, error) {
+	for _, m := range []Medium{MediumWireless, MediumWired, MediumBoth} {
+		if s == m.String() {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid medium %q, must be wireless, wired or both", s)
 }
 
 // Base is the definition that each network always has
@@ -56,6 +159,10 @@ type Base struct {
 	Certs cert.Certificates
 	// SSIDs are the list of SSIDs
 	SSIDs []SSID
+	// Wired are the wired 802.1X networks, IEEE8023 in the EAP config
+	Wired []Wired
+	// ConsortiumOIs are the Passpoint (Hotspot 2.0) roaming consortium OIs as lowercase hex, e.g. 5a03ba0000
+	ConsortiumOIs []string
 	// ServerIDs is the list of server names
 	ServerIDs []string
 	// ProviderInfo is the ProviderInfo info
@@ -63,6 +170,47 @@ type Base struct {
 	// AnonIdentity is the anonymous identity found in the EAP config, OuterIdentity in clientcredentials
 	// This is optional as when it's not set it will be set to the username in case of non TLS
 	AnonIdentity string
+	// ValidUntil is the time after which the EAP config should not be used anymore, ValidUntil in the EAP config
+	// This is nil if the EAP config does not expire
+	ValidUntil *time.Time
+}
+
+// Expiry returns the time after which the EAP config should not be used anymore
+func (b Base) Expiry() *time.Time {
+	return b.ValidUntil
+}
+
+// Media returns the media on which the network can be installed
+func (b Base) Media() Medium {
+	var m Medium
+	if len(b.SSIDs) > 0 {
+		m |= MediumWireless
+	}
+	if len(b.Wired) > 0 {
+		m |= MediumWired
+	}
+	return m
+}
+
+// Restrict removes the networks that are not on the given medium
+func (b *Base) Restrict(m Medium) {
+	if m&MediumWireless == 0 {
+		b.SSIDs = nil
+	}
+	if m&MediumWired == 0 {
+		b.Wired = nil
+	}
+}
+
+// SetPMF overrides the PMF policy of the SSIDs that was derived from the EAP config
+// The WPA3-Enterprise 192-bit mode cannot work without protected management frames so these SSIDs keep requiring it
+func (b *Base) SetPMF(p PMF) {
+	for i := range b.SSIDs {
+		if b.SSIDs[i].SuiteB192 {
+			continue
+		}
+		b.SSIDs[i].PMF = p
+	}
 }
 
 // Credentials is the credentials belonging to the Non TLS network
@@ -78,6 +226,9 @@ type Credentials struct {
 	Suffix string
 	// Password is the string that is configured as the RADIUS password for the connection
 	Password string
+	// OneTimeToken is whether the password is a one-time token, e.g. for EAP-GTC
+	// The token is never asked or saved, NetworkManager asks for a new one through its secret agent every time it connects
+	OneTimeToken bool
 }
 
 // NonTLS is a structure for creating a network that has EAP method not TLS
@@ -89,6 +240,17 @@ type NonTLS struct {
 	MethodType method.Type
 	// InnerAuth is the inner authentication method
 	InnerAuth inner.Type
+	// InnerCert is the client certificate for the inner EAP-TLS authentication, see inner.EapTLS
+	// It is nil for the other inner authentication methods or if it still has to be asked from the user
+	InnerCert *cert.ClientCert
+	// RawInnerPKCS12 is the raw PKCS12 container of the inner client certificate from the EAP config
+	RawInnerPKCS12 string
+	// InnerPassphrase is the passphrase that encrypts the inner client certificate
+	InnerPassphrase string
+	// PAC is the EAP-FAST Protected Access Credential from the EAP config, this can be empty if it is provisioned
+	PAC string
+	// ProvisionPAC is whether the EAP-FAST PAC can be provisioned in-band when connecting
+	ProvisionPAC bool
 }
 
 // Method returns the method for the NonTLS network
@@ -96,6 +258,14 @@ func (n *NonTLS) Method() method.Type {
 	return n.MethodType
 }
 
+// MethodName returns the name of the method and the inner authentication method for the NonTLS network, e.g. ttls-pap
+func (n *NonTLS) MethodName() string {
+	if in := n.InnerAuth.String(); in != "" {
+		return n.MethodType.String() + "-" + in
+	}
+	return n.MethodType.String()
+}
+
 // ProviderInfo returns the provider info for the NonTLS network
 func (n *NonTLS) ProviderInfo() ProviderInfo {
 	return n.Base.ProviderInfo
@@ -119,6 +289,11 @@ func (t *TLS) Method() method.Type {
 	return method.TLS
 }
 
+// MethodName returns the name of the method for the TLS network
+func (t *TLS) MethodName() string {
+	return t.Method().String()
+}
+
 // ProviderInfo returns the provider info for the TLS network
 func (t *TLS) ProviderInfo() ProviderInfo {
 	return t.Base.ProviderInfo
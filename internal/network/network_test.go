@@ -0,0 +1,97 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/geteduroam/linux-app/internal/utilsx"
+)
+
+func TestMedia(t *testing.T) {
+	full := Base{
+		SSIDs: []SSID{{Value: "eduroam", MinRSN: "CCMP"}},
+		Wired: []Wired{{NetworkID: "lab"}},
+	}
+	cases := []struct {
+		base      Base
+		restrict  Medium
+		want      Medium
+		wantSSIDs int
+		wantWired int
+	}{
+		{base: full, restrict: MediumBoth, want: MediumBoth, wantSSIDs: 1, wantWired: 1},
+		{base: full, restrict: MediumWireless, want: MediumWireless, wantSSIDs: 1, wantWired: 0},
+		{base: full, restrict: MediumWired, want: MediumWired, wantSSIDs: 0, wantWired: 1},
+		{base: Base{SSIDs: full.SSIDs}, restrict: MediumBoth, want: MediumWireless, wantSSIDs: 1, wantWired: 0},
+	}
+	for _, c := range cases {
+		b := c.base
+		b.Restrict(c.restrict)
+		if b.Media() != c.want {
+			t.Fatalf("media not equal for restrict: %v, want: %v, got: %v", c.restrict, c.want, b.Media())
+		}
+		if len(b.SSIDs) != c.wantSSIDs || len(b.Wired) != c.wantWired {
+			t.Fatalf("networks not equal for restrict: %v, want: %d SSIDs and %d wired, got: %v and %v", c.restrict, c.wantSSIDs, c.wantWired, b.SSIDs, b.Wired)
+		}
+	}
+}
+
+func TestParseMedium(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Medium
+		err   string
+	}{
+		{input: "wireless", want: MediumWireless},
+		{input: "wired", want: MediumWired},
+		{input: "both", want: MediumBoth},
+		{input: "ethernet", err: "invalid medium \"ethernet\", must be wireless, wired or both"},
+	}
+	for _, c := range cases {
+		got, err := ParseMedium(c.input)
+		if utilsx.ErrorString(err) != c.err {
+			t.Fatalf("error not equal for: %q, want: %v, got: %v", c.input, c.err, err)
+		}
+		if got != c.want {
+			t.Fatalf("medium not equal for: %q, want: %v, got: %v", c.input, c.want, got)
+		}
+	}
+}
+
+func TestSetPMF(t *testing.T) {
+	b := Base{
+		SSIDs: []SSID{
+			{Value: "eduroam", MinRSN: "CCMP", PMF: PMFOptional},
+			{Value: "eduroam-192", MinRSN: "GCMP-256", PMF: PMFRequired, SuiteB192: true},
+		},
+	}
+	b.SetPMF(PMFDisable)
+	want := []PMF{PMFDisable, PMFRequired}
+	for i, s := range b.SSIDs {
+		if s.PMF != want[i] {
+			t.Fatalf("PMF not equal for SSID: %v, want: %v, got: %v", s.Value, want[i], s.PMF)
+		}
+	}
+}
+
+func TestParsePMF(t *testing.T) {
+	cases := []struct {
+		input string
+		want  PMF
+		err   string
+	}{
+		{input: "default", want: PMFDefault},
+		{input: "disable", want: PMFDisable},
+		{input: "optional", want: PMFOptional},
+		{input: "required", want: PMFRequired},
+		{input: "on", err: "invalid PMF policy \"on\", must be default, disable, optional or required"},
+	}
+	for _, c := range cases {
+		got, err := ParsePMF(c.input)
+		if utilsx.ErrorString(err) != c.err {
+			t.Fatalf("error not equal for: %q, want: %v, got: %v", c.input, c.err, err)
+		}
+		if got != c.want {
+			t.Fatalf("PMF not equal for: %q, want: %v, got: %v", c.input, c.want, got)
+		}
+	}
+}
@@ -42,6 +42,15 @@ func (b *Base) CallReturn(ret interface{}, method string, args ...interface{}) e
 	return b.object.Call(method, 0, args...).Store(ret)
 }
 
+// GetProperty gets a DBUS property by its name including the interface, e.g. fi.w1.wpa_supplicant1.EapMethods
+func (b *Base) GetProperty(name string) (interface{}, error) {
+	v, err := b.object.GetProperty(name)
+	if err != nil {
+		return nil, err
+	}
+	return v.Value(), nil
+}
+
 // Path returns the DBUS object path
 func (b *Base) Path() dbus.ObjectPath {
 	return b.object.Path()
@@ -23,21 +23,36 @@ const (
 // SettingsArgs is the arguments for connection settings
 type SettingsArgs map[string]map[string]interface{}
 
-// UUID returns the UUID for the connection
-func (s SettingsArgs) UUID() (string, error) {
+// connectionValue returns a string value from the connection section of the settings
+func (s SettingsArgs) connectionValue(key string) (string, error) {
 	c, ok := s["connection"]
 	if !ok {
 		return "", errors.New("no connection value in connection settings map")
 	}
-	uuid, ok := c["uuid"]
+	v, ok := c[key]
 	if !ok {
-		return "", errors.New("no uuid in connection map")
+		return "", fmt.Errorf("no %s in connection map", key)
 	}
-	uuidS, ok := uuid.(string)
+	vS, ok := v.(string)
 	if !ok {
-		return "", fmt.Errorf("uuid is not a string: %T", uuid)
+		return "", fmt.Errorf("%s is not a string: %T", key, v)
 	}
-	return uuidS, nil
+	return vS, nil
+}
+
+// UUID returns the UUID for the connection
+func (s SettingsArgs) UUID() (string, error) {
+	return s.connectionValue("uuid")
+}
+
+// ID returns the human readable ID for the connection
+func (s SettingsArgs) ID() (string, error) {
+	return s.connectionValue("id")
+}
+
+// Type returns the type for the connection, e.g. 802-11-wireless or 802-3-ethernet
+func (s SettingsArgs) Type() (string, error) {
+	return s.connectionValue("type")
 }
 
 // SSID returns the SSID for the connection
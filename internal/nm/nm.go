@@ -4,6 +4,7 @@ package nm
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/user"
 	"path/filepath"
 	"slices"
@@ -13,8 +14,11 @@ import (
 
 	"github.com/geteduroam/linux-app/internal/config"
 	"github.com/geteduroam/linux-app/internal/network"
+	"github.com/geteduroam/linux-app/internal/network/cert"
+	"github.com/geteduroam/linux-app/internal/network/inner"
 	"github.com/geteduroam/linux-app/internal/network/method"
 	"github.com/geteduroam/linux-app/internal/nm/connection"
+	"github.com/geteduroam/linux-app/internal/nm/supplicant"
 	"github.com/geteduroam/linux-app/internal/variant"
 )
 
@@ -72,35 +76,43 @@ func createCon(pUUID string, args connection.SettingsArgs) (*connection.Connecti
 	return s.AddConnection(args)
 }
 
-// installBase contains the code for creating a network with NetworkManager
-// This contains the shared network settings between TLS and NonTLS
-// The specific 8021x settings are given as an argument `specific`
-func installBaseSSID(n network.Base, ssid network.SSID, specifics map[string]interface{}, pUUID string) (string, error) {
-	fID := fmt.Sprintf("%s (from %s)", ssid.Value, variant.DisplayName)
+// conn is a NetworkManager connection that is created for each SSID and wired network of an authentication method
+type conn struct {
+	// specifics are the 8021x settings of the authentication method
+	specifics map[string]interface{}
+	// priority is the autoconnect priority, the preferred method has the highest
+	// The lowest priority is 1, just above the default 0,
+	// such that connections for existing eduroam profiles (and default priority) will not be used
+	// Wired connections do not autoconnect, see wiredSettings
+	priority int32
+	// suffix is added to the connection ID of the fallback methods to make it unique, e.g. ttls-pap
+	// It is empty for the preferred method
+	suffix string
+}
+
+// connID returns the NetworkManager connection ID for the name of a network, e.g. the SSID
+func (c conn) connID(name string) string {
+	if c.suffix == "" {
+		return fmt.Sprintf("%s (from %s)", name, variant.DisplayName)
+	}
+	return fmt.Sprintf("%s (from %s, %s)", name, variant.DisplayName, c.suffix)
+}
+
+// baseSettings returns the settings that are shared between all connections, wireless and wired
+// The connection type and ID are given as arguments
+// The specific 8021x settings and the autoconnect priority are given by the connection `c`
+func baseSettings(n network.Base, ctype string, id string, c conn) (connection.SettingsArgs, error) {
 	cUser, err := user.Current()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	sCon := map[string]interface{}{
-		// the priority is 1, just above the default 0
-		// such that connections for existing eduroam profiles (and default priority)
-		// will not be used
-		"autoconnect-priority": 1,
+		"autoconnect-priority": c.priority,
 		"permissions": []string{
 			fmt.Sprintf("user:%s", cUser.Username),
 		},
-		"type": "802-11-wireless",
-		"id":   fID,
-	}
-	sWifi := map[string]interface{}{
-		"ssid":     []byte(ssid.Value),
-		"security": "802-11-wireless-security",
-	}
-	sWsec := map[string]interface{}{
-		"key-mgmt": "wpa-eap",
-		"proto":    []string{"rsn"},
-		"pairwise": []string{strings.ToLower(ssid.MinRSN)},
-		"group":    []string{strings.ToLower(ssid.MinRSN)},
+		"type": ctype,
+		"id":   id,
 	}
 	sIP4 := map[string]interface{}{
 		"method": "auto",
@@ -108,37 +120,47 @@ func installBaseSSID(n network.Base, ssid network.SSID, specifics map[string]int
 	sIP6 := map[string]interface{}{
 		"method": "auto",
 	}
-	var sids []string
+	s8021x := map[string]interface{}{}
+	// methods without server certificates, e.g. EAP-PWD, do not have CAs
+	if len(n.Certs) > 0 {
+		var sids []string
 
-	for _, sid := range n.ServerIDs {
-		v := fmt.Sprintf("DNS:%s", sid)
-		sids = append(sids, v)
-	}
-	caBasePath, err := config.Directory()
-	if err != nil {
-		return "", err
-	}
-	err = n.Certs.ToDir(caBasePath)
-	if err != nil {
-		return "", err
-	}
-	s8021x := map[string]interface{}{
-		"ca-path":            filepath.Join(caBasePath, "ca"),
-		"altsubject-matches": sids,
+		for _, sid := range n.ServerIDs {
+			v := fmt.Sprintf("DNS:%s", sid)
+			sids = append(sids, v)
+		}
+		caBasePath, err := config.Directory()
+		if err != nil {
+			return nil, err
+		}
+		err = n.Certs.ToDir(caBasePath)
+		if err != nil {
+			return nil, err
+		}
+		s8021x["ca-path"] = filepath.Join(caBasePath, "ca")
+		s8021x["altsubject-matches"] = sids
+		// inner EAP-TLS verifies the same server
+		if _, ok := c.specifics["phase2-client-cert"]; ok {
+			s8021x["phase2-ca-path"] = filepath.Join(caBasePath, "ca")
+			s8021x["phase2-altsubject-matches"] = sids
+		}
 	}
 	// add the network specific settings
-	for k, v := range specifics {
+	for k, v := range c.specifics {
 		s8021x[k] = v
 	}
 
-	settings := map[string]map[string]interface{}{
-		"connection":               sCon,
-		"802-11-wireless":          sWifi,
-		"802-11-wireless-security": sWsec,
-		"802-1x":                   s8021x,
-		"ipv4":                     sIP4,
-		"ipv6":                     sIP6,
-	}
+	return connection.SettingsArgs{
+		"connection": sCon,
+		"802-1x":     s8021x,
+		"ipv4":       sIP4,
+		"ipv6":       sIP6,
+	}, nil
+}
+
+// installSettings creates or updates the connection with the settings
+// It returns the UUID of the connection
+func installSettings(settings connection.SettingsArgs, pUUID string) (string, error) {
 	con, err := createCon(pUUID, settings)
 	if err != nil {
 		return "", err
@@ -155,13 +177,118 @@ func installBaseSSID(n network.Base, ssid network.SSID, specifics map[string]int
 	return uuid, nil
 }
 
-// installBase contains the code for creating a network with NetworkManager
+// installBaseSSID contains the code for creating a Wi-Fi network with NetworkManager
+// This contains the shared network settings between TLS and NonTLS
+// The specific 8021x settings are given by the connection `c`
+func installBaseSSID(n network.Base, ssid network.SSID, c conn, pUUID string) (string, error) {
+	settings, err := baseSettings(n, "802-11-wireless", c.connID(ssid.Value), c)
+	if err != nil {
+		return "", err
+	}
+	settings["802-11-wireless"] = map[string]interface{}{
+		"ssid":     []byte(ssid.Value),
+		"security": "802-11-wireless-security",
+	}
+	settings["802-11-wireless-security"] = wirelessSecurity(ssid)
+	return installSettings(settings, pUUID)
+}
+
+// wirelessSecurity returns the 802-11-wireless-security settings for the SSID
+// The WPA3-Enterprise 192-bit mode uses the wpa-eap-suite-b-192 key management, all other networks use wpa-eap.
+// With wpa-eap NetworkManager also allows the WPA3-Enterprise SHA256 key management when PMF is enabled.
+// NetworkManager can only restrict the pairwise and group ciphers to TKIP and CCMP.
+// For a minimum of GCMP or GCMP-256 the ciphers are thus left empty such that they are negotiated,
+// restricting them to CCMP would make it impossible to connect to a GCMP only network
+func wirelessSecurity(ssid network.SSID) map[string]interface{} {
+	keyMgmt := "wpa-eap"
+	if ssid.SuiteB192 {
+		keyMgmt = "wpa-eap-suite-b-192"
+	}
+	sec := map[string]interface{}{
+		"key-mgmt": keyMgmt,
+		"proto":    []string{"rsn"},
+	}
+	// the PMF values are the same as the ones of NetworkManager
+	if ssid.PMF != network.PMFDefault {
+		sec["pmf"] = int32(ssid.PMF)
+	}
+	switch ssid.MinRSN {
+	case "GCMP", "GCMP-256":
+		slog.Debug("Not restricting the ciphers as NetworkManager does not support GCMP ciphers", "ssid", ssid.Value, "minRSN", ssid.MinRSN)
+	default:
+		sec["pairwise"] = []string{strings.ToLower(ssid.MinRSN)}
+		sec["group"] = []string{strings.ToLower(ssid.MinRSN)}
+	}
+	return sec
+}
+
+// wiredID returns the NetworkManager connection ID for a wired network
+func (c conn) wiredID(w network.Wired) string {
+	name := w.NetworkID
+	if name == "" {
+		name = variant.ProfileName
+	}
+	return c.connID(name + " wired")
+}
+
+// WiredIDs returns the NetworkManager connection IDs of the wired networks of the preferred authentication method
+// These are not activated automatically, see wiredSettings
+func WiredIDs(n network.Base) []string {
+	ids := make([]string, len(n.Wired))
+	for i, w := range n.Wired {
+		ids[i] = conn{}.wiredID(w)
+	}
+	return ids
+}
+
+// wiredSettings returns the settings for a wired 802.1X network
+// The connection is not bound to an interface, so it would apply to every wired network, e.g. at home.
+// Autoconnect is thus disabled as otherwise every wired network without 802.1X first waits for the 802.1X timeout
+func wiredSettings(n network.Base, w network.Wired, c conn) (connection.SettingsArgs, error) {
+	settings, err := baseSettings(n, "802-3-ethernet", c.wiredID(w), c)
+	if err != nil {
+		return nil, err
+	}
+	settings["connection"]["autoconnect"] = false
+	// the defaults of the ethernet settings are fine, the 802-1x settings make it use 802.1X
+	settings["802-3-ethernet"] = map[string]interface{}{}
+	return settings, nil
+}
+
+// installBaseWired contains the code for creating a wired 802.1X network with NetworkManager
 // This contains the shared network settings between TLS and NonTLS
-// The specific 8021x settings are given as an argument `specific`
-// It loops through all SSIDs and creates different networks for each
-func installBase(n network.Base, specifics map[string]interface{}, pUUIDs []string) ([]string, error) {
-	// get  a mapping from ssids to the accompanying uuid
-	ssidMap := make(map[string]string)
+// The specific 8021x settings are given by the connection `c`
+func installBaseWired(n network.Base, w network.Wired, c conn, pUUID string) (string, error) {
+	settings, err := wiredSettings(n, w, c)
+	if err != nil {
+		return "", err
+	}
+	return installSettings(settings, pUUID)
+}
+
+// prevKey returns the key to map a previous connection to a network
+// The connections are mapped by their ID as this is unique for each SSID, wired network and authentication method
+func prevKey(settings connection.SettingsArgs) (string, error) {
+	t, err := settings.Type()
+	if err != nil {
+		return "", err
+	}
+	id, err := settings.ID()
+	if err != nil {
+		return "", err
+	}
+	switch t {
+	case "802-11-wireless":
+		return "ssid:" + id, nil
+	case "802-3-ethernet":
+		return "wired:" + id, nil
+	}
+	return "", fmt.Errorf("unsupported connection type: %s", t)
+}
+
+// previousUUIDs returns a mapping from the previous connections to their UUID, see prevKey
+func previousUUIDs(pUUIDs []string) map[string]string {
+	prevMap := make(map[string]string)
 	for _, puuid := range pUUIDs {
 		con, err := PreviousCon(puuid)
 		if err != nil {
@@ -173,88 +300,189 @@ func installBase(n network.Base, specifics map[string]interface{}, pUUIDs []stri
 			slog.Debug("failed getting settings con for UUID map", "error", err)
 			continue
 		}
-		ssid, err := settings.SSID()
+		key, err := prevKey(settings)
 		if err != nil {
-			slog.Debug("failed getting ssid from settings con for UUID map", "error", err)
+			slog.Debug("failed getting key from settings con for UUID map", "error", err)
 			continue
 		}
-		if ssid != "" {
-			ssidMap[ssid] = puuid
-		}
+		prevMap[key] = puuid
 	}
+	return prevMap
+}
 
-	var added []string
+// keys returns the keys of the connections of the network, see prevKey
+func keys(n network.Base, c conn) []string {
+	var ks []string
+	for _, ssid := range n.SSIDs {
+		ks = append(ks, "ssid:"+c.connID(ssid.Value))
+	}
+	for _, w := range n.Wired {
+		ks = append(ks, "wired:"+c.wiredID(w))
+	}
+	return ks
+}
 
-	// remove connections no longer needed
-	defer func() {
-		for ssid, puuid := range ssidMap {
-			if slices.Contains(added, puuid) {
-				continue
-			}
-			slog.Debug("connection does not contain previous UUID, removing the connection", "ssid", ssid, "uuid", puuid, "added", added)
-			con, err := PreviousCon(puuid)
-			if err == nil {
-				err := con.Delete()
-				if err != nil {
-					slog.Debug("failed to delete connection", "error", err)
-				}
-			} else {
-				slog.Debug("previous connection does not exist, not removing", "error", err)
-			}
-		}
-	}()
+// installBase contains the code for creating a network with NetworkManager
+// This contains the shared network settings between TLS and NonTLS
+// The specific 8021x settings are given by the connection `c`
+// It loops through all SSIDs and wired networks and creates different connections for each
+// The previous connections that are updated are looked up in prevMap
+// If a connection fails to install, the others are still installed and the previous connection is kept
+// It returns the uuids of the connections and the joined errors
+func installBase(n network.Base, c conn, prevMap map[string]string) ([]string, error) {
+	// NetworkManager has no connection settings for the roaming consortium OIs of wpa_supplicant
+	if len(n.ConsortiumOIs) > 0 {
+		slog.Info("Hotspot 2.0 (Passpoint) is not configured as NetworkManager cannot add roaming consortium OIs, only the SSIDs are added", "ois", n.ConsortiumOIs)
+	}
 
-	// add new connections
-	for _, ssid := range n.SSIDs {
-		uuid := ssidMap[ssid.Value]
-		guuid, err := installBaseSSID(n, ssid, specifics, uuid)
+	var uuids []string
+	var errs []error
+	install := func(key string, f func(pUUID string) (string, error)) {
+		puuid := prevMap[key]
+		guuid, err := f(puuid)
 		if err != nil {
-			return added, err
+			errs = append(errs, fmt.Errorf("connection %q: %w", key, err))
+			if puuid != "" {
+				uuids = append(uuids, puuid)
+			}
+			return
 		}
-		added = append(added, guuid)
+		uuids = append(uuids, guuid)
 	}
-
-	return added, nil
+	for _, ssid := range n.SSIDs {
+		install("ssid:"+c.connID(ssid.Value), func(pUUID string) (string, error) {
+			return installBaseSSID(n, ssid, c, pUUID)
+		})
+	}
+	for _, w := range n.Wired {
+		install("wired:"+c.wiredID(w), func(pUUID string) (string, error) {
+			return installBaseWired(n, w, c, pUUID)
+		})
+	}
+	return uuids, errors.Join(errs...)
 }
 
-// Install installs a non TLS network and returns an error if it cannot configure it
-// Right now it adds a new profile that is not automatically added
-// It returns the uuid if the connection was added successfully
-func Install(n network.NonTLS, pUUIDs []string) ([]string, error) {
+// nonTLSSettings returns the 8021x settings for a non TLS network
+// The client certificate and private key for inner EAP-TLS are written to files with the suffix, see certFiles
+func nonTLSSettings(n *network.NonTLS, suffix string) (map[string]interface{}, error) {
 	s8021x := map[string]interface{}{
 		"eap": []string{
 			n.Method().String(),
 		},
 		"anonymous-identity": n.AnonIdentity,
 		"identity":           n.Credentials.Username,
-		"password":           n.Credentials.Password,
-		"password-flags":     0,
 	}
-	if n.InnerAuth.EAP() && n.MethodType == method.TTLS {
+	switch {
+	// inner EAP-TLS has no password
+	case n.InnerAuth.NeedsCertificate():
+	// a one-time token can only be used once, so it is not saved (NM_SETTING_SECRET_FLAG_NOT_SAVED)
+	// NetworkManager asks for a new one through its secret agent for every connection, without a secret agent it cannot connect
+	case n.Credentials.OneTimeToken:
+		s8021x["password-flags"] = 2
+	default:
+		s8021x["password"] = n.Credentials.Password
+		s8021x["password-flags"] = 0
+	}
+	switch {
+	// EAP-PWD has no inner authentication
+	case !n.MethodType.HasInner():
+	case n.InnerAuth.EAP() && n.MethodType == method.TTLS:
 		s8021x["phase2-autheap"] = n.InnerAuth.String()
-	} else {
+	// PEAP uses phase2-auth for the EAP methods as well
+	default:
 		s8021x["phase2-auth"] = n.InnerAuth.String()
 	}
-	return installBase(n.Base, s8021x, pUUIDs)
+	if n.MethodType == method.FAST {
+		pac, err := pacFile(n.PAC, suffix)
+		if err != nil {
+			return nil, err
+		}
+		s8021x["pac-file"] = pac
+		s8021x["phase1-fast-provisioning"] = fastProvisioning(n)
+	}
+	if n.InnerAuth.NeedsCertificate() {
+		ccFile, pkFile, pwd, err := certFiles(n.InnerCert, suffix)
+		if err != nil {
+			return nil, err
+		}
+		s8021x["phase2-client-cert"] = ccFile
+		s8021x["phase2-private-key"] = pkFile
+		s8021x["phase2-private-key-password"] = pwd
+		s8021x["phase2-private-key-password-flags"] = 0
+	}
+	return s8021x, nil
 }
 
-// InstallTLS installs a TLS network and returns an error if it cannot configure it
-// Right now it adds a new profile that is not automatically added
-// It returns the uuid if the connection was added successfully
-func InstallTLS(n network.TLS, pUUIDs []string) ([]string, error) {
-	ccFile, err := encodeFileBytes("client-cert.pem", n.ClientCert.ToPEM())
+// pacFile returns the path of the EAP-FAST PAC file in the config directory named after the suffix, such that fallbacks do not overwrite it
+// The PAC from the EAP config is written to it, if there is none the provisioned PAC is stored in it when connecting.
+// A previously provisioned PAC is kept
+func pacFile(pac string, suffix string) (string, error) {
+	name := "fast.pac"
+	if suffix != "" {
+		name = suffix + ".pac"
+	}
+	if pac != "" {
+		return config.WriteFile(name, []byte(pac))
+	}
+	dir, err := config.Directory()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	pkp, pwd, err := n.ClientCert.PrivateKeyPEMEnc()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// fastProvisioning returns the NetworkManager phase1-fast-provisioning value for an EAP-FAST network
+// If the PAC can be provisioned, this is done authenticated when we have the CAs to verify the server.
+// Unauthenticated provisioning is only used without CAs and with inner EAP-MSCHAPv2
+func fastProvisioning(n *network.NonTLS) string {
+	switch {
+	case !n.ProvisionPAC:
+		return "0"
+	case len(n.Certs) > 0:
+		return "2"
+	case n.InnerAuth == inner.EapMschapv2 || n.InnerAuth == inner.EapPeapMschapv2:
+		slog.Warn("Provisioning the EAP-FAST PAC unauthenticated as the profile has no server side CA", "method", n.MethodName())
+		return "1"
+	default:
+		return "0"
+	}
+}
+
+// certFiles writes the client certificate and the encrypted private key to files with the suffix, such that fallbacks do not overwrite them
+// It returns the paths encoded for NetworkManager and the password of the private key
+func certFiles(cc *cert.ClientCert, suffix string) ([]byte, []byte, string, error) {
+	name := func(f string) string {
+		if suffix == "" {
+			return f + ".pem"
+		}
+		return fmt.Sprintf("%s-%s.pem", f, suffix)
+	}
+	ccFile, err := encodeFileBytes(name("client-cert"), cc.ToPEM())
 	if err != nil {
-		return nil, err
+		return nil, nil, "", err
+	}
+	pkp, pwd, err := cc.PrivateKeyPEMEnc()
+	if err != nil {
+		return nil, nil, "", err
 	}
-	pkFile, err := encodeFileBytes("private-key.pem", pkp)
+	pkFile, err := encodeFileBytes(name("private-key"), pkp)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return ccFile, pkFile, pwd, nil
+}
+
+// tlsSettings returns the 8021x settings for a TLS network
+// The client certificate and private key are written to files with the suffix, see certFiles
+func tlsSettings(n *network.TLS, suffix string) (map[string]interface{}, error) {
+	ccFile, pkFile, pwd, err := certFiles(n.ClientCert, suffix)
 	if err != nil {
 		return nil, err
 	}
-	s8021x := map[string]interface{}{
+	return map[string]interface{}{
 		"eap": []string{
 			"tls",
 		},
@@ -263,6 +491,117 @@ func InstallTLS(n network.TLS, pUUIDs []string) ([]string, error) {
 		"private-key":                pkFile,
 		"private-key-password":       pwd,
 		"private-key-password-flags": 0,
+	}, nil
+}
+
+// newConn returns the connection settings for the network at index i of the total networks in order of preference
+// The preferred network gets the highest autoconnect priority, the fallbacks descending priorities down to 1
+// The priority and suffix are also set when an error is returned
+func newConn(n network.Network, i int, total int) (conn, error) {
+	c := conn{priority: int32(total - i)}
+	if i > 0 {
+		c.suffix = n.MethodName()
+	}
+	var err error
+	switch t := n.(type) {
+	case *network.NonTLS:
+		c.specifics, err = nonTLSSettings(t, c.suffix)
+	case *network.TLS:
+		c.specifics, err = tlsSettings(t, c.suffix)
+	default:
+		err = fmt.Errorf("unsupported network: %T", n)
+	}
+	return c, err
+}
+
+// base returns the base of the network
+func base(n network.Network) network.Base {
+	switch t := n.(type) {
+	case *network.NonTLS:
+		return t.Base
+	case *network.TLS:
+		return t.Base
+	}
+	return network.Base{}
+}
+
+// SupportsMethod returns an error with the reason if NetworkManager cannot connect with the EAP method
+// NetworkManager uses wpa_supplicant for the authentication, which can be built without EAP-TEAP
+func SupportsMethod(m method.Type) error {
+	if m != method.TEAP {
+		return nil
+	}
+	methods, err := supplicant.EAPMethods()
+	if err != nil {
+		return fmt.Errorf("failed to check if wpa_supplicant supports EAP-TEAP: %w", err)
+	}
+	if !slices.Contains(methods, "TEAP") {
+		return errors.New("wpa_supplicant is built without EAP-TEAP")
+	}
+	return nil
+}
+
+// ErrPreferred is wrapped by the error of Install if the preferred network failed to install
+// The other networks are only fallbacks
+var ErrPreferred = errors.New("failed to install the preferred authentication method")
+
+// Install installs the networks and returns an error if it cannot configure them
+// The networks are the authentication methods of a profile in order of preference,
+// each of them gets its own connections with descending autoconnect priorities such that the others are fallbacks
+// Each network is installed on its own, if one fails, e.g. because NetworkManager does not support its method, the others are still installed
+// The previous connections are updated and the ones that are no longer needed are removed,
+// the previous connections of networks that fail to install are kept
+// It returns the uuids of the connections and the joined errors of the networks that failed, see ErrPreferred
+func Install(ns []network.Network, pUUIDs []string) ([]string, error) {
+	// get a mapping from ssids and wired networks to the accompanying uuid
+	prevMap := previousUUIDs(pUUIDs)
+
+	var uuids []string
+	var errs []error
+	for i, n := range ns {
+		b := base(n)
+		c, err := newConn(n, i, len(ns))
+		if err == nil {
+			var got []string
+			got, err = installBase(b, c, prevMap)
+			uuids = append(uuids, got...)
+		} else {
+			// keep the previous connections of this network
+			for _, k := range keys(b, c) {
+				if puuid, ok := prevMap[k]; ok {
+					uuids = append(uuids, puuid)
+				}
+			}
+		}
+		// wpa_supplicant can support EAP-TEAP while NetworkManager does not, see SupportsMethod
+		if err != nil && n.Method() == method.TEAP {
+			err = fmt.Errorf("NetworkManager needs to support teap in the 802-1x settings: %w", err)
+		}
+		if err != nil {
+			slog.Warn("Failed to install the authentication method", "method", n.MethodName(), "error", err)
+			if i == 0 {
+				errs = append(errs, fmt.Errorf("%w %s: %w", ErrPreferred, n.MethodName(), err))
+			} else {
+				errs = append(errs, fmt.Errorf("failed to install the fallback authentication method %s: %w", n.MethodName(), err))
+			}
+		}
+	}
+
+	// remove connections no longer needed
+	for key, puuid := range prevMap {
+		if slices.Contains(uuids, puuid) {
+			continue
+		}
+		slog.Debug("connection does not contain previous UUID, removing the connection", "key", key, "uuid", puuid, "uuids", uuids)
+		con, err := PreviousCon(puuid)
+		if err == nil {
+			err := con.Delete()
+			if err != nil {
+				slog.Debug("failed to delete connection", "error", err)
+			}
+		} else {
+			slog.Debug("previous connection does not exist, not removing", "error", err)
+		}
 	}
-	return installBase(n.Base, s8021x, pUUIDs)
+	return uuids, errors.Join(errs...)
 }
@@ -0,0 +1,300 @@
+package nm
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/geteduroam/linux-app/internal/config"
+	"github.com/geteduroam/linux-app/internal/network"
+	"github.com/geteduroam/linux-app/internal/network/cert"
+	"github.com/geteduroam/linux-app/internal/network/inner"
+	"github.com/geteduroam/linux-app/internal/network/method"
+	"github.com/geteduroam/linux-app/internal/variant"
+)
+
+func TestWirelessSecurity(t *testing.T) {
+	cases := []struct {
+		ssid network.SSID
+		want map[string]interface{}
+	}{
+		{
+			ssid: network.SSID{Value: "eduroam", MinRSN: "CCMP"},
+			want: map[string]interface{}{
+				"key-mgmt": "wpa-eap",
+				"proto":    []string{"rsn"},
+				"pairwise": []string{"ccmp"},
+				"group":    []string{"ccmp"},
+			},
+		},
+		{
+			// WPA3-Enterprise transition mode
+			ssid: network.SSID{Value: "eduroam", MinRSN: "CCMP", PMF: network.PMFOptional},
+			want: map[string]interface{}{
+				"key-mgmt": "wpa-eap",
+				"proto":    []string{"rsn"},
+				"pmf":      int32(2),
+				"pairwise": []string{"ccmp"},
+				"group":    []string{"ccmp"},
+			},
+		},
+		{
+			// WPA3-Enterprise only mode
+			ssid: network.SSID{Value: "eduroam", MinRSN: "GCMP", PMF: network.PMFRequired},
+			want: map[string]interface{}{
+				"key-mgmt": "wpa-eap",
+				"proto":    []string{"rsn"},
+				"pmf":      int32(3),
+			},
+		},
+		{
+			// WPA3-Enterprise 192-bit mode
+			ssid: network.SSID{Value: "eduroam", MinRSN: "GCMP-256", PMF: network.PMFRequired, SuiteB192: true},
+			want: map[string]interface{}{
+				"key-mgmt": "wpa-eap-suite-b-192",
+				"proto":    []string{"rsn"},
+				"pmf":      int32(3),
+			},
+		},
+		{
+			ssid: network.SSID{Value: "eduroam", MinRSN: "CCMP", PMF: network.PMFDisable},
+			want: map[string]interface{}{
+				"key-mgmt": "wpa-eap",
+				"proto":    []string{"rsn"},
+				"pmf":      int32(1),
+				"pairwise": []string{"ccmp"},
+				"group":    []string{"ccmp"},
+			},
+		},
+	}
+	for _, c := range cases {
+		got := wirelessSecurity(c.ssid)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("wireless security settings not equal for: %+v, want: %v, got: %v", c.ssid, c.want, got)
+		}
+	}
+}
+
+func TestConnections(t *testing.T) {
+	ns := []network.Network{
+		&network.NonTLS{MethodType: method.PEAP, InnerAuth: inner.EapMschapv2},
+		&network.NonTLS{MethodType: method.TTLS, InnerAuth: inner.Pap},
+		&network.NonTLS{MethodType: method.TTLS, InnerAuth: inner.EapMschapv2},
+	}
+	cs := make([]conn, len(ns))
+	for i, n := range ns {
+		c, err := newConn(n, i, len(ns))
+		if err != nil {
+			t.Fatalf("failed getting connection: %d, %v", i, err)
+		}
+		cs[i] = c
+	}
+	wantPriorities := []int32{3, 2, 1}
+	wantIDs := []string{
+		fmt.Sprintf("eduroam (from %s)", variant.DisplayName),
+		fmt.Sprintf("eduroam (from %s, ttls-pap)", variant.DisplayName),
+		fmt.Sprintf("eduroam (from %s, ttls-mschapv2)", variant.DisplayName),
+	}
+	wantPhase2 := []string{"phase2-auth", "phase2-auth", "phase2-autheap"}
+	for i, c := range cs {
+		if c.priority != wantPriorities[i] {
+			t.Fatalf("priority not equal for: %d, want: %v, got: %v", i, wantPriorities[i], c.priority)
+		}
+		if got := c.connID("eduroam"); got != wantIDs[i] {
+			t.Fatalf("connection ID not equal for: %d, want: %v, got: %v", i, wantIDs[i], got)
+		}
+		if _, ok := c.specifics[wantPhase2[i]]; !ok {
+			t.Fatalf("phase 2 setting %v not found for: %d, got: %v", wantPhase2[i], i, c.specifics)
+		}
+		// the previous connections are matched by these keys
+		b := network.Base{SSIDs: []network.SSID{{Value: "eduroam"}}}
+		if got := keys(b, c); !reflect.DeepEqual(got, []string{"ssid:" + wantIDs[i]}) {
+			t.Fatalf("keys not equal for: %d, want: %v, got: %v", i, []string{"ssid:" + wantIDs[i]}, got)
+		}
+	}
+}
+
+func TestPWDSettings(t *testing.T) {
+	n := &network.NonTLS{
+		Credentials: network.Credentials{
+			Username: "user@pwd.example.org",
+			Password: "secret",
+		},
+		MethodType: method.PWD,
+	}
+	want := map[string]interface{}{
+		"eap":                []string{"pwd"},
+		"anonymous-identity": "",
+		"identity":           "user@pwd.example.org",
+		"password":           "secret",
+		"password-flags":     0,
+	}
+	got, err := nonTLSSettings(n, "")
+	if err != nil {
+		t.Fatalf("failed getting 802-1x settings: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("802-1x settings not equal, want: %v, got: %v", want, got)
+	}
+}
+
+func TestTEAPSettings(t *testing.T) {
+	n := &network.NonTLS{
+		Base: network.Base{
+			AnonIdentity: "anonymous@teap.example.org",
+		},
+		Credentials: network.Credentials{
+			Username: "user@teap.example.org",
+			Password: "secret",
+		},
+		MethodType: method.TEAP,
+		InnerAuth:  inner.EapMschapv2,
+	}
+	want := map[string]interface{}{
+		"eap":                []string{"teap"},
+		"anonymous-identity": "anonymous@teap.example.org",
+		"identity":           "user@teap.example.org",
+		"password":           "secret",
+		"password-flags":     0,
+		"phase2-auth":        "mschapv2",
+	}
+	got, err := nonTLSSettings(n, "")
+	if err != nil {
+		t.Fatalf("failed getting 802-1x settings: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("802-1x settings not equal, want: %v, got: %v", want, got)
+	}
+}
+
+func TestGTCSettings(t *testing.T) {
+	cases := []struct {
+		mt   method.Type
+		key  string
+		want string
+	}{
+		{mt: method.TTLS, key: "phase2-autheap", want: "gtc"},
+		{mt: method.PEAP, key: "phase2-auth", want: "gtc"},
+	}
+	for _, c := range cases {
+		n := &network.NonTLS{
+			Credentials: network.Credentials{
+				Username:     "user@example.org",
+				Password:     "123456",
+				OneTimeToken: true,
+			},
+			MethodType: c.mt,
+			InnerAuth:  inner.EapGtc,
+		}
+		got, err := nonTLSSettings(n, "")
+		if err != nil {
+			t.Fatalf("failed getting 802-1x settings: %v", err)
+		}
+		if got[c.key] != c.want {
+			t.Fatalf("%s not equal for: %v, want: %v, got: %v", c.key, c.mt, c.want, got)
+		}
+		// the token is not saved, NetworkManager asks for a new one
+		if _, ok := got["password"]; ok {
+			t.Fatalf("one-time token is saved for: %v, got: %v", c.mt, got["password"])
+		}
+		if got["password-flags"] != 2 {
+			t.Fatalf("password flags not equal for: %v, want: 2, got: %v", c.mt, got["password-flags"])
+		}
+	}
+}
+
+func TestFASTSettings(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dir)
+	cdir, err := config.Directory()
+	if err != nil {
+		t.Fatalf("failed getting config directory: %v", err)
+	}
+	cases := []struct {
+		n            network.NonTLS
+		suffix       string
+		provisioning string
+		pac          string
+	}{
+		// the PAC from the EAP config is written
+		{
+			n:            network.NonTLS{MethodType: method.FAST, InnerAuth: inner.EapMschapv2, PAC: "pac data"},
+			provisioning: "0",
+			pac:          "pac data",
+		},
+		// unauthenticated provisioning without CAs
+		{
+			n:            network.NonTLS{MethodType: method.FAST, InnerAuth: inner.EapMschapv2, ProvisionPAC: true},
+			suffix:       "fast-mschapv2",
+			provisioning: "1",
+		},
+		// no unauthenticated provisioning without EAP-MSCHAPv2
+		{
+			n:            network.NonTLS{MethodType: method.FAST, InnerAuth: inner.EapGtc, ProvisionPAC: true},
+			suffix:       "fast-gtc",
+			provisioning: "0",
+		},
+		// authenticated provisioning with CAs
+		{
+			n: network.NonTLS{
+				Base:         network.Base{Certs: cert.Certificates{&x509.Certificate{}}},
+				MethodType:   method.FAST,
+				InnerAuth:    inner.EapMschapv2,
+				ProvisionPAC: true,
+			},
+			suffix:       "fast-mschapv2",
+			provisioning: "2",
+		},
+	}
+	for _, c := range cases {
+		got, err := nonTLSSettings(&c.n, c.suffix)
+		if err != nil {
+			t.Fatalf("failed getting 802-1x settings: %v", err)
+		}
+		if got["phase1-fast-provisioning"] != c.provisioning {
+			t.Fatalf("provisioning not equal, want: %v, got: %v", c.provisioning, got["phase1-fast-provisioning"])
+		}
+		if got["phase2-auth"] != c.n.InnerAuth.String() {
+			t.Fatalf("phase2-auth not equal, want: %v, got: %v", c.n.InnerAuth, got["phase2-auth"])
+		}
+		pac, ok := got["pac-file"].(string)
+		if !ok || filepath.Dir(pac) != cdir {
+			t.Fatalf("PAC file is not in the config directory: %v, got: %v", cdir, got["pac-file"])
+		}
+		b, err := os.ReadFile(pac)
+		if c.pac == "" {
+			if err == nil {
+				t.Fatalf("PAC file exists without a PAC: %v", pac)
+			}
+			continue
+		}
+		if string(b) != c.pac {
+			t.Fatalf("PAC not equal, want: %v, got: %v", c.pac, string(b))
+		}
+	}
+}
+
+func TestWiredSettings(t *testing.T) {
+	n := network.Base{
+		Wired: []network.Wired{{NetworkID: "campus"}},
+	}
+	c := conn{specifics: map[string]interface{}{"eap": []string{"peap"}}, priority: 1}
+	got, err := wiredSettings(n, n.Wired[0], c)
+	if err != nil {
+		t.Fatalf("failed getting wired settings: %v", err)
+	}
+	// wired connections apply to every wired network so they do not autoconnect
+	if got["connection"]["autoconnect"] != false {
+		t.Fatalf("autoconnect not disabled, got: %v", got["connection"]["autoconnect"])
+	}
+	want := fmt.Sprintf("campus wired (from %s)", variant.DisplayName)
+	if got["connection"]["id"] != want {
+		t.Fatalf("connection ID not equal, want: %v, got: %v", want, got["connection"]["id"])
+	}
+	if ids := WiredIDs(n); !reflect.DeepEqual(ids, []string{want}) {
+		t.Fatalf("wired IDs not equal, want: %v, got: %v", []string{want}, ids)
+	}
+}
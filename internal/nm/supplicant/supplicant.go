@@ -0,0 +1,36 @@
+// Package supplicant implements the parts of the wpa_supplicant DBUS API that we need
+// NetworkManager uses wpa_supplicant for the EAP authentication
+package supplicant
+
+import (
+	"fmt"
+
+	"github.com/geteduroam/linux-app/internal/nm/base"
+)
+
+const (
+	// Interface is the DBUS interface for wpa_supplicant
+	Interface = "fi.w1.wpa_supplicant1"
+	// ObjectPath is the DBUS Object Path for wpa_supplicant
+	ObjectPath = "/fi/w1/wpa_supplicant1"
+
+	// EAPMethodsProperty is the property with the EAP methods that wpa_supplicant is built with
+	EAPMethodsProperty = Interface + ".EapMethods"
+)
+
+// EAPMethods returns the names of the EAP methods that wpa_supplicant is built with, e.g. TTLS, PEAP or TEAP
+func EAPMethods() ([]string, error) {
+	var b base.Base
+	if err := b.Init(Interface, ObjectPath); err != nil {
+		return nil, err
+	}
+	v, err := b.GetProperty(EAPMethodsProperty)
+	if err != nil {
+		return nil, err
+	}
+	methods, ok := v.([]string)
+	if !ok {
+		return nil, fmt.Errorf("EAP methods is not a list of strings: %T", v)
+	}
+	return methods, nil
+}
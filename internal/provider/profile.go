@@ -9,9 +9,10 @@ import (
 	"net/http"
 	"net/url"
 	"os/exec"
-	"time"
 
 	"codeberg.org/jwijenbergh/eduoauth-go/v2"
+
+	"github.com/geteduroam/linux-app/internal/httpclient"
 )
 
 // Profile is the profile from discovery
@@ -38,6 +39,19 @@ const (
 	OAuthFlow
 )
 
+// String returns the string representation of the flow
+func (f FlowCode) String() string {
+	switch f {
+	case DirectFlow:
+		return "direct"
+	case RedirectFlow:
+		return "redirect"
+	case OAuthFlow:
+		return "oauth"
+	}
+	return ""
+}
+
 // Flow gets the flow we need to go through to get the EAP config
 // See: https://github.com/geteduroam/cattenbak/blob/481e243f22b40e1d8d48ecac2b85705b8cb48494/cattenbak.py#L68
 func (p *Profile) Flow() FlowCode {
@@ -86,18 +100,17 @@ func readResponse(res *http.Response) ([]byte, error) {
 
 // EAPDirect Gets an EAP config using the direct flow
 // It returns the byte array of the EAP config and an error if there is one
-func (p *Profile) EAPDirect() ([]byte, error) {
+func (p *Profile) EAPDirect(ctx context.Context) ([]byte, error) {
 	if p.CachedResponse != nil {
 		return p.CachedResponse, nil
 	}
 	// Do request
-	req, err := http.NewRequest("GET", p.EapConfigEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.EapConfigEndpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	client := http.Client{Timeout: 10 * time.Second}
-	res, err := client.Do(req)
+	res, err := httpclient.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -114,17 +127,16 @@ type letsWifiEndpoints struct {
 	} `json:"http://letswifi.app/api#v2"`
 }
 
-func (p *Profile) getLetsWifiEndpoints() ([]byte, error) {
-	client := http.Client{Timeout: 10 * time.Second}
+func (p *Profile) getLetsWifiEndpoints(ctx context.Context) ([]byte, error) {
 	if p.LetsWifiEndpoint == "" {
 		return nil, errors.New("no Let's Wifi endpoint found")
 	}
-	req, err := http.NewRequest("GET", p.LetsWifiEndpoint, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", p.LetsWifiEndpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
-	res, err := client.Do(req)
+	res, err := httpclient.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +152,7 @@ func (p *Profile) EAPOAuth(ctx context.Context, auth func(authURL string)) ([]by
 	var err error
 	b := p.CachedResponse
 	if b == nil {
-		b, err = p.getLetsWifiEndpoints()
+		b, err = p.getLetsWifiEndpoints(ctx)
 		if err != nil {
 			return nil, err
 		}
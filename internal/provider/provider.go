@@ -6,13 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
-	"sort"
+	"slices"
 	"strings"
-	"time"
 
-	"github.com/geteduroam/linux-app/internal/utilsx"
+	"github.com/geteduroam/linux-app/internal/httpclient"
+	"github.com/geteduroam/linux-app/internal/lang"
 	"golang.org/x/text/language"
 )
 
@@ -36,52 +35,63 @@ func (ls LocalizedStrings) Corpus() string {
 	return corpus.String()
 }
 
-var systemLanguage = language.English
+// userCountry is the ISO 3166-1 alpha-2 country code of the user that is set with SetCountry
+// If it is nil, the country is taken from the language of the user, see Country
+var userCountry *string
 
-func setSystemLanguage() {
-	lang := os.Getenv("LANG")
-	if lang == "" {
-		lang = os.Getenv("LC_ALL")
+// systemCountry gets the country from the language of the user
+// This is done when the country is needed as the language can be configured after startup, e.g. with a --lang flag, see lang.Configure
+func systemCountry() string {
+	// only use the region if it is explicitly given, e.g. nl_NL
+	// we do not want to guess a country from the language only
+	if r, conf := lang.System().Region(); conf == language.Exact {
+		return r.String()
 	}
-	first := strings.Split(lang, ".")[0]
-	tag, err := language.Parse(first)
+	return ""
+}
+
+// SetCountry sets the country of the user that is used to rank providers
+// The country is an ISO 3166-1 alpha-2 code, e.g. NL
+// An empty country disables ranking by country
+func SetCountry(country string) error {
+	if country == "" {
+		userCountry = &country
+		return nil
+	}
+	r, err := language.ParseRegion(country)
 	if err != nil {
-		// TODO: log invalid language
-		return
+		return fmt.Errorf("invalid country %q: %w", country, err)
 	}
-	systemLanguage = tag
+	c := r.String()
+	userCountry = &c
+	return nil
 }
 
-// Get gets a string based on the system language
+// Country returns the country of the user that is used to rank providers
+// Providers from this country are ranked first, if it is empty no country ranking is done
+func Country() string {
+	if userCountry != nil {
+		return *userCountry
+	}
+	return systemCountry()
+}
+
+// Get gets a string based on the languages of the user
+// If no language matches, the first non-empty string is returned
 func (ls LocalizedStrings) Get() string {
-	// first get the non-empty values
-	var disp string
-	var conf language.Confidence
-	m := language.NewMatcher([]language.Tag{systemLanguage})
+	langs := make([]string, len(ls))
+	for i, val := range ls {
+		langs[i] = val.Lang
+	}
+	if i, ok := lang.Best(langs); ok {
+		return ls[i].Display
+	}
 	for _, val := range ls {
-		// no display yet
-		if disp == "" {
-			disp = val.Display
-			// we don't continue here as we still need to store the confidence
-		}
-		if val.Lang == "" {
-			continue
-		}
-		t, err := language.Parse(val.Lang)
-		// tag is invalid, just continue with the next option
-		if err != nil {
-			continue
-		}
-
-		// the confidence that this matches
-		// is higher than the current confidence
-		_, _, got := m.Match(t)
-		if got > conf {
-			disp = val.Display
-			conf = got
+		if val.Display != "" {
+			return val.Display
 		}
 	}
-	return disp
+	return ""
 }
 
 // Provider is the info for a single eduroam/getgovroam etc provider
@@ -99,6 +109,14 @@ type Provider struct {
 // Providers is the list of providers
 type Providers []Provider
 
+// matchesWord returns whether or not the search matches a whole word in the lowercase name
+func matchesWord(lname string, search string) bool {
+	lower := strings.ToLower(search)
+	escaped := regexp.QuoteMeta(lower)
+	match := regexp.MustCompile(fmt.Sprintf("(^|[\\P{L}])%s[\\P{L}]", escaped))
+	return match.MatchString(lname)
+}
+
 // SortNames sorts two localized strings
 func SortNames(a LocalizedStrings, b LocalizedStrings, search string) int {
 	la := strings.ToLower(a.Corpus())
@@ -109,11 +127,8 @@ func SortNames(a LocalizedStrings, b LocalizedStrings, search string) int {
 	if search == "" {
 		return bd
 	}
-	lower := strings.ToLower(search)
-	escaped := regexp.QuoteMeta(lower)
-	match := regexp.MustCompile(fmt.Sprintf("(^|[\\P{L}])%s[\\P{L}]", escaped))
-	mi := match.MatchString(la)
-	mj := match.MatchString(lb)
+	mi := matchesWord(la, search)
+	mj := matchesWord(lb, search)
 	if mi == mj {
 		// tiebreak on alphabetical order
 		return bd
@@ -123,59 +138,89 @@ func SortNames(a LocalizedStrings, b LocalizedStrings, search string) int {
 	return 1
 }
 
-// ByName is the struct that implements by name sorting
-type ByName struct {
-	// Providers is the list of providers
-	Providers Providers
-	// Search is the search string
-	Search string
+// SortScored sorts two providers using their search scores, see Provider.Match
+// The scores are computed once per search as scoring a search is too expensive to do for every comparison
+// Providers that match the search better are sorted first
+// Then the providers from the country of the user are sorted first, see SetCountry
+// It tiebreaks on alphabetical order
+func SortScored(a Provider, sa int, b Provider, sb int) int {
+	return compareRanked(ranked{p: a, score: sa, corpus: strings.ToLower(a.Name.Corpus())}, ranked{p: b, score: sb, corpus: strings.ToLower(b.Name.Corpus())}, Country())
 }
 
-// Len returns the length of the ByName sort
-func (s ByName) Len() int { return len(s.Providers) }
-
-// Swap swaps two providers
-func (s ByName) Swap(i, j int) { s.Providers[i], s.Providers[j] = s.Providers[j], s.Providers[i] }
-
-// Less sorts the providers
-func (s ByName) Less(i, j int) bool {
-	diff := SortNames(s.Providers[i].Name, s.Providers[j].Name, s.Search)
-	// if i is less than j, diff returns less than 0
-	return diff < 0
+// ranked is a provider with the values that are used for sorting
+// These are computed once as scoring a search is too expensive to do for every comparison
+type ranked struct {
+	p Provider
+	// score is the score of the search, see Provider.Match
+	score int
+	// corpus is the lowercase name, see LocalizedStrings.Corpus
+	corpus string
 }
 
-// FilterSingle searches inside the corpus
-func FilterSingle(name LocalizedStrings, search string) bool {
-	l1, err1 := utilsx.RemoveDiacritics(strings.ToLower(name.Corpus()))
-	l2, err2 := utilsx.RemoveDiacritics(strings.ToLower(search))
-	if err1 != nil || err2 != nil {
-		return false
+// compareRanked compares two ranked providers, see SortScored
+func compareRanked(a ranked, b ranked, country string) int {
+	if a.score != b.score {
+		if a.score > b.score {
+			return -1
+		}
+		return 1
 	}
-	if !strings.Contains(l1, l2) {
-		return false
+	if country != "" {
+		ci := strings.EqualFold(a.p.Country, country)
+		cj := strings.EqualFold(b.p.Country, country)
+		if ci != cj {
+			if ci {
+				return -1
+			}
+			return 1
+		}
 	}
-	return true
+	return strings.Compare(a.corpus, b.corpus)
 }
 
 // FilterSort filters and sorts a list of providers
 // The sorting is done in reverse as this is used in the CLI where the most relevant providers should be shown at the bottom
 func (i *Providers) FilterSort(search string) *Providers {
-	x := ByName{
-		Providers: Providers{},
-		Search:    search,
+	var rs []ranked
+	for _, p := range *i {
+		if score := p.Match(search).Score; score > scoreNone {
+			rs = append(rs, ranked{p: p, score: score, corpus: strings.ToLower(p.Name.Corpus())})
+		}
+	}
+	country := Country()
+	slices.SortFunc(rs, func(a, b ranked) int {
+		// reversed
+		return compareRanked(b, a, country)
+	})
+	x := make(Providers, len(rs))
+	for idx, r := range rs {
+		x[idx] = r.p
 	}
-	for _, i := range *i {
-		if FilterSingle(i.Name, search) {
-			x.Providers = append(x.Providers, i)
+	return &x
+}
+
+// ByID gets the provider with the exact ID
+func (i *Providers) ByID(id string) (*Provider, error) {
+	for idx := range *i {
+		if (*i)[idx].ID == id {
+			return &(*i)[idx], nil
+		}
+	}
+	return nil, fmt.Errorf("no provider found with ID: %q", id)
+}
+
+// ProfileByID gets the profile of the provider with the exact ID
+func (p *Provider) ProfileByID(id string) (*Profile, error) {
+	for idx := range p.Profiles {
+		if p.Profiles[idx].ID == id {
+			return &p.Profiles[idx], nil
 		}
 	}
-	sort.Sort(sort.Reverse(ByName(x)))
-	return &x.Providers
+	return nil, fmt.Errorf("no profile found with ID: %q for provider: %q", id, p.ID)
 }
 
 // Custom gets provider info using a custom URL
 func Custom(ctx context.Context, query string) (*Provider, error) {
-	client := http.Client{Timeout: 10 * time.Second}
 	// parse URL and add scheme
 	u, err := url.Parse(query)
 	if err != nil {
@@ -192,7 +237,7 @@ func Custom(ctx context.Context, query string) (*Provider, error) {
 	req.Header.Set("Accept", "application/json")
 	req.Header.Add("Accept", "application/eap-config")
 
-	resp, err := client.Do(req)
+	resp, err := httpclient.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -223,7 +268,3 @@ func Custom(ctx context.Context, query string) (*Provider, error) {
 	p.Profiles = []Profile{prof}
 	return p, nil
 }
-
-func init() {
-	setSystemLanguage()
-}
@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/geteduroam/linux-app/internal/lang"
 	"github.com/geteduroam/linux-app/internal/utilsx"
 	"golang.org/x/text/language"
 )
@@ -55,7 +57,7 @@ func TestLocalizedStrings(t *testing.T) {
 	}
 
 	for _, c := range cases {
-		systemLanguage = c.lang
+		lang.Set(c.lang)
 		got := c.input.Get()
 		if got != c.want {
 			t.Fatalf("Got: %s, Not equal to Want: %s", got, c.want)
@@ -103,6 +105,18 @@ func TestFilterSort(t *testing.T) {
 			length: 2,
 			want:   "Provider Twö",
 		},
+		{
+			// Filter with a typo
+			input:  "porvider one",
+			length: 1,
+			want:   "Provider One",
+		},
+		{
+			// Filter on initials
+			input:  "PO",
+			length: 1,
+			want:   "Provider One",
+		},
 	}
 
 	for _, c := range cases {
@@ -115,6 +129,183 @@ func TestFilterSort(t *testing.T) {
 	}
 }
 
+func TestScore(t *testing.T) {
+	tud := LocalizedStrings{{Display: "Technische Universiteit Delft", Lang: "nl"}, {Display: "Delft University of Technology", Lang: "en"}}
+	mit := LocalizedStrings{{Display: "Massachusetts Institute of Technology"}}
+	cases := []struct {
+		name   LocalizedStrings
+		search string
+		want   int
+	}{
+		// Empty search matches everything
+		{name: tud, search: "", want: scoreEmpty},
+		// Whole words
+		{name: tud, search: "delft", want: scoreWord},
+		{name: tud, search: "University of", want: scoreWord},
+		// Substring
+		{name: tud, search: "niversiteit", want: scoreSubstring},
+		// Prefixes of words in any order
+		{name: tud, search: "tech delf", want: scorePrefix},
+		// Abbreviations
+		{name: tud, search: "TUD", want: scoreInitials},
+		{name: tud, search: "DUOT", want: scoreInitials},
+		{name: mit, search: "MIT", want: scoreInitialsPartial},
+		// Typos
+		{name: tud, search: "Univeristy", want: scoreFuzzy - scoreFuzzyEdit},
+		{name: tud, search: "Delft Univrsity", want: scoreFuzzy - scoreFuzzyEdit},
+		{name: tud, search: "Tecnhische Unversiteit", want: scoreFuzzy - 2*scoreFuzzyEdit},
+		// Diacritics
+		{name: LocalizedStrings{{Display: "Université de Genève"}}, search: "geneve", want: scoreWord},
+		// No match
+		{name: tud, search: "Amsterdam", want: scoreNone},
+		{name: tud, search: "UvA", want: scoreNone},
+		// Short words are not matched fuzzily
+		{name: tud, search: "dlf", want: scoreNone},
+	}
+
+	for _, c := range cases {
+		got := Score(c.name, c.search)
+		if got != c.want {
+			t.Fatalf("Score for %q: %d, Want: %d", c.search, got, c.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	p := Provider{
+		ID:   "cat_1234",
+		Name: LocalizedStrings{{Display: "Example University"}},
+		Profiles: []Profile{
+			{
+				Name:             LocalizedStrings{{Display: "Staff and Students"}},
+				LetsWifiEndpoint: "https://wifi.example.edu/.well-known/letswifi.json",
+			},
+			{
+				Name:              LocalizedStrings{{Display: "Visitors"}},
+				EapConfigEndpoint: "https://cat.eduroam.org/user/API.php?action=downloadInstaller&profile=1",
+			},
+		},
+	}
+
+	if got := p.Realms(); !reflect.DeepEqual(got, []string{"example.edu"}) {
+		t.Fatalf("Realms: %v, Want: %v", got, []string{"example.edu"})
+	}
+
+	cases := []struct {
+		search string
+		field  Field
+		value  string
+		match  bool
+	}{
+		{search: "example", field: NameField, value: "Example University", match: true},
+		{search: "visitors", field: ProfileField, value: "Visitors", match: true},
+		{search: "@student.example.edu", field: RealmField, value: "example.edu", match: true},
+		{search: "john@example.edu", field: RealmField, value: "example.edu", match: true},
+		{search: "@example.org", match: false},
+		// the shared CAT host is not a realm
+		{search: "eduroam.org", match: false},
+	}
+	for _, c := range cases {
+		m := p.Match(c.search)
+		if (m.Score > 0) != c.match {
+			t.Fatalf("Match for %q: %v, Want match: %v", c.search, m, c.match)
+		}
+		if !c.match {
+			continue
+		}
+		if m.Field != c.field || m.Value != c.value {
+			t.Fatalf("Match for %q: %v %q, Want: %v %q", c.search, m.Field, m.Value, c.field, c.value)
+		}
+	}
+}
+
+func TestSortProviders(t *testing.T) {
+	i := Providers{
+		{Country: "DE", Name: LocalizedStrings{{Display: "University A"}}},
+		{Country: "NL", Name: LocalizedStrings{{Display: "University B"}}},
+		{Country: "NL", Name: LocalizedStrings{{Display: "Universityplace C"}}},
+		{Country: "DE", Name: LocalizedStrings{{Display: "University D"}}},
+	}
+
+	cases := []struct {
+		country string
+		search  string
+		want    []string
+	}{
+		{
+			// No country, alphabetical order
+			country: "",
+			search:  "university",
+			want:    []string{"University A", "University B", "University D", "Universityplace C"},
+		},
+		{
+			// Providers from the country first, the name match still has precedence
+			country: "nl",
+			search:  "university",
+			want:    []string{"University B", "University A", "University D", "Universityplace C"},
+		},
+		{
+			// No search, only the country
+			country: "DE",
+			search:  "",
+			want:    []string{"University A", "University D", "University B", "Universityplace C"},
+		},
+	}
+
+	for _, c := range cases {
+		if err := SetCountry(c.country); err != nil {
+			t.Fatalf("failed setting country: %v", err)
+		}
+		// FilterSort returns the list in reverse
+		result := *i.FilterSort(c.search)
+		var got []string
+		for idx := len(result) - 1; idx >= 0; idx-- {
+			got = append(got, result[idx].Name.Get())
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Fatalf("Result: %v, Want: %v, country: %v", got, c.want, c.country)
+		}
+	}
+	if err := SetCountry("foo"); err == nil {
+		t.Fatalf("Expected an error for an invalid country")
+	}
+	if err := SetCountry(""); err != nil {
+		t.Fatalf("failed resetting country: %v", err)
+	}
+}
+
+func TestByID(t *testing.T) {
+	i := Providers{
+		{ID: "provider_one", Profiles: []Profile{{ID: "profile_one"}, {ID: "profile_two"}}},
+		{ID: "provider_two"},
+	}
+
+	p, err := i.ByID("provider_one")
+	if err != nil {
+		t.Fatalf("failed getting provider by ID: %v", err)
+	}
+	if p.ID != "provider_one" {
+		t.Fatalf("Result: %v, Want: %v", p.ID, "provider_one")
+	}
+	prof, err := p.ProfileByID("profile_two")
+	if err != nil {
+		t.Fatalf("failed getting profile by ID: %v", err)
+	}
+	if prof.ID != "profile_two" {
+		t.Fatalf("Result: %v, Want: %v", prof.ID, "profile_two")
+	}
+
+	// No partial matches
+	_, err = i.ByID("provider")
+	if utilsx.ErrorString(err) != `no provider found with ID: "provider"` {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err = p.ProfileByID("profile")
+	if utilsx.ErrorString(err) != `no profile found with ID: "profile" for provider: "provider_one"` {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
 func TestFlow(t *testing.T) {
 	p := Profile{
 		EapConfigEndpoint:    "https://provider1.geteduroam.nl/api/eap-config/",
@@ -191,3 +382,52 @@ func TestRedirectURI(t *testing.T) {
 		}
 	}
 }
+
+func TestFlowString(t *testing.T) {
+	cases := []struct {
+		profile Profile
+		want    string
+	}{
+		{profile: Profile{Type: "eap-config"}, want: "direct"},
+		{profile: Profile{Type: "webview"}, want: "redirect"},
+		{profile: Profile{Type: "letswifi"}, want: "oauth"},
+	}
+	for _, c := range cases {
+		if got := c.profile.Flow().String(); got != c.want {
+			t.Fatalf("Flow string not equal, want: %v, got: %v", c.want, got)
+		}
+	}
+}
+
+func TestCountryFromLanguage(t *testing.T) {
+	prev := userCountry
+	defer func() {
+		userCountry = prev
+		lang.Set()
+	}()
+	userCountry = nil
+	cases := []struct {
+		lang language.Tag
+		want string
+	}{
+		// the region is explicitly given
+		{lang: language.MustParse("nl-NL"), want: "NL"},
+		// the language was changed, e.g. with --lang
+		{lang: language.MustParse("de-AT"), want: "AT"},
+		// we do not guess a country from the language only
+		{lang: language.Dutch, want: ""},
+	}
+	for _, c := range cases {
+		lang.Set(c.lang)
+		if got := Country(); got != c.want {
+			t.Fatalf("country for %v not equal, want: %v, got: %v", c.lang, c.want, got)
+		}
+	}
+	// an explicit country overrides the language
+	if err := SetCountry("be"); err != nil {
+		t.Fatalf("failed setting country: %v", err)
+	}
+	if got := Country(); got != "BE" {
+		t.Fatalf("country not equal, want: BE, got: %v", got)
+	}
+}
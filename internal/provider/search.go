@@ -0,0 +1,367 @@
+package provider
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/geteduroam/linux-app/internal/utilsx"
+)
+
+// The scores that are given for the different kinds of matches
+// A higher score means a better match
+const (
+	// scoreNone is the score when the search does not match
+	scoreNone = 0
+	// scoreEmpty is the score when the search is empty, everything matches equally
+	scoreEmpty = 1
+	// scoreWord is the score when the search matches whole words in the name
+	scoreWord = 100
+	// scoreSubstring is the score when the search is a substring of the name
+	scoreSubstring = 90
+	// scorePrefix is the score when each search word is a prefix of a word in the name
+	scorePrefix = 80
+	// scoreInitials is the score when the search is equal to the initials of the name
+	scoreInitials = 70
+	// scoreInitialsPartial is the score when the search is a part of the initials of the name, starting with the first one
+	scoreInitialsPartial = 60
+	// scoreFuzzy is the score when each search word is within the edit distance of a word in the name
+	// For each edit, scoreFuzzyEdit is subtracted
+	scoreFuzzy     = 50
+	scoreFuzzyEdit = 10
+)
+
+// normalize lowercases the string and removes diacritics
+func normalize(s string) string {
+	l := strings.ToLower(s)
+	r, err := utilsx.RemoveDiacritics(l)
+	if err != nil {
+		return l
+	}
+	return r
+}
+
+// tokenize splits a normalized string into words
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// maxEdits returns the edit distance that is tolerated for a search word
+// Short words are not matched fuzzily as then almost everything would match
+func maxEdits(word []rune) int {
+	switch {
+	case len(word) < 4:
+		return 0
+	case len(word) < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and b
+// This is the Levenshtein distance where a transposition of two adjacent characters also counts as one edit
+func editDistance(a []rune, b []rune) int {
+	// d[i][j] is the distance between the first i runes of a and the first j runes of b
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// wordDistance returns the smallest edit distance between the search word and a name word
+// The search word is compared to the whole name word and to its prefixes of about the same length
+// such that a search that is not yet fully typed also matches
+func wordDistance(search []rune, name []rune) int {
+	best := editDistance(search, name)
+	for l := len(search) - 1; l <= len(search)+1; l++ {
+		if l <= 0 || l >= len(name) {
+			continue
+		}
+		best = min(best, editDistance(search, name[:l]))
+	}
+	return best
+}
+
+// isInitials returns whether or not the search consists of the initials of the name words
+// It returns true as the second value if the search is equal to all initials
+func isInitials(search string, names []string) (bool, bool) {
+	var initials []rune
+	for _, n := range names {
+		initials = append(initials, []rune(n)[0])
+	}
+	s := []rune(search)
+	if len(s) < 2 || len(s) > len(initials) || s[0] != initials[0] {
+		return false, false
+	}
+	if string(s) == string(initials) {
+		return true, true
+	}
+	// the search must be a subsequence of the initials, e.g. MIT for Massachusetts Institute of Technology
+	i := 0
+	for _, r := range initials {
+		if i < len(s) && s[i] == r {
+			i++
+		}
+	}
+	return i == len(s), false
+}
+
+// scoreSingle returns the score of a normalized search for a single normalized name
+func scoreSingle(name string, search string) int {
+	nt := tokenize(name)
+	st := tokenize(search)
+	if len(nt) == 0 || len(st) == 0 {
+		return scoreNone
+	}
+	if strings.Contains(name, search) {
+		padded := " " + strings.Join(nt, " ") + " "
+		if strings.Contains(padded, " "+strings.Join(st, " ")+" ") {
+			return scoreWord
+		}
+		return scoreSubstring
+	}
+
+	// each search word is the prefix of a name word
+	prefix := true
+	for _, s := range st {
+		found := false
+		for _, n := range nt {
+			if strings.HasPrefix(n, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			prefix = false
+			break
+		}
+	}
+	if prefix {
+		return scorePrefix
+	}
+
+	// an abbreviation, e.g. TUD for Technische Universiteit Delft
+	if len(st) == 1 {
+		if ok, full := isInitials(st[0], nt); ok {
+			if full {
+				return scoreInitials
+			}
+			return scoreInitialsPartial
+		}
+	}
+
+	// each search word is close to a name word, e.g. a typo
+	edits := 0
+	for _, s := range st {
+		sr := []rune(s)
+		best := -1
+		for _, n := range nt {
+			d := wordDistance(sr, []rune(n))
+			if best == -1 || d < best {
+				best = d
+			}
+		}
+		if best > maxEdits(sr) {
+			return scoreNone
+		}
+		edits += best
+	}
+	return max(scoreFuzzy-edits*scoreFuzzyEdit, scoreEmpty+1)
+}
+
+// Score returns how well the search matches the localized names
+// Diacritics and case are ignored, and the search may contain typos or be an abbreviation of the name
+// A score of zero means no match, a higher score means a better match
+func Score(name LocalizedStrings, search string) int {
+	s := normalize(strings.TrimSpace(search))
+	if s == "" {
+		return scoreEmpty
+	}
+	best := scoreNone
+	for _, v := range name {
+		best = max(best, scoreSingle(normalize(v.Display), s))
+	}
+	return best
+}
+
+// sharedHosts are the hosts that are shared between many providers
+// These are not used as a realm hint as they do not say anything about the provider
+var sharedHosts = []string{
+	"eduroam.org",
+	"eduroam.app",
+	"geteduroam.app",
+	"govroam.nl",
+	"getgovroam.nl",
+}
+
+// serviceLabels are the first labels of a host that are stripped to get the realm hint, e.g. wifi.example.edu becomes example.edu
+var serviceLabels = []string{
+	"cat",
+	"eduroam",
+	"geteduroam",
+	"getgovroam",
+	"letswifi",
+	"portal",
+	"wifi",
+	"www",
+}
+
+// realmHint returns the realm hint for a domain or an empty string if it is not a viable hint
+func realmHint(domain string) string {
+	d := strings.Trim(strings.ToLower(domain), ".")
+	if strings.Count(d, ".") < 1 {
+		return ""
+	}
+	for _, s := range sharedHosts {
+		if d == s || strings.HasSuffix(d, "."+s) {
+			return ""
+		}
+	}
+	first, rest, _ := strings.Cut(d, ".")
+	// only strip the service label if what remains is still a domain
+	if slices.Contains(serviceLabels, first) && strings.Contains(rest, ".") {
+		return rest
+	}
+	return d
+}
+
+// Realms returns the realm and domain hints for the provider
+// These are derived from the domains in the provider ID and the hosts of the profile endpoints
+func (p *Provider) Realms() []string {
+	var realms []string
+	add := func(domain string) {
+		h := realmHint(domain)
+		if h != "" && !slices.Contains(realms, h) {
+			realms = append(realms, h)
+		}
+	}
+	for _, part := range strings.FieldsFunc(p.ID, func(r rune) bool { return r == '_' || r == '@' || r == '/' }) {
+		add(part)
+	}
+	for _, prof := range p.Profiles {
+		for _, ep := range []string{prof.EapConfigEndpoint, prof.LetsWifiEndpoint, prof.WebviewEndpoint} {
+			if ep == "" {
+				continue
+			}
+			u, err := url.Parse(ep)
+			if err != nil {
+				continue
+			}
+			add(u.Hostname())
+		}
+	}
+	return realms
+}
+
+// isDomainSearch returns the search as a domain if it looks like a realm, e.g. @student.example.edu or example.edu
+func isDomainSearch(search string) (string, bool) {
+	s := strings.ToLower(strings.TrimSpace(search))
+	// the user entered the full username
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		s = s[i+1:]
+	}
+	if strings.ContainsAny(s, " /") || !strings.Contains(strings.Trim(s, "."), ".") {
+		return "", false
+	}
+	return strings.Trim(s, "."), true
+}
+
+// scoreRealm returns the score for a domain search and a realm hint
+// It matches if the domain is equal to the realm or one is a subdomain of the other
+func scoreRealm(realm string, domain string) int {
+	if realm == domain {
+		return scoreWord
+	}
+	if strings.HasSuffix(domain, "."+realm) || strings.HasSuffix(realm, "."+domain) {
+		return scoreSubstring
+	}
+	return scoreNone
+}
+
+// Field is the field of a provider that produced a search match
+type Field int8
+
+const (
+	// NameField is a match on the provider name
+	NameField Field = iota
+	// ProfileField is a match on one of the profile names
+	ProfileField
+	// RealmField is a match on one of the realm hints, see Realms
+	RealmField
+)
+
+// String returns the string representation of the field
+func (f Field) String() string {
+	switch f {
+	case NameField:
+		return "name"
+	case ProfileField:
+		return "profile"
+	case RealmField:
+		return "realm"
+	}
+	return ""
+}
+
+// profilePenalty is subtracted from the score of a profile name match
+// such that a provider matching by name is preferred over one that only matches by profile
+const profilePenalty = 5
+
+// Match is the result of searching a provider
+type Match struct {
+	// Field is the field that produced the match
+	Field Field
+	// Value is the value of the field that matched, e.g. the profile name or the realm
+	Value string
+	// Score is how well the search matched, zero means no match, see Score
+	Score int
+}
+
+// Match searches the provider name, the profile names and the realm hints
+// It returns the best match
+func (p *Provider) Match(search string) Match {
+	best := Match{
+		Field: NameField,
+		Value: p.Name.Get(),
+		Score: Score(p.Name, search),
+	}
+	// an empty search matches every field equally, we keep the name
+	if best.Score == scoreEmpty {
+		return best
+	}
+	for _, prof := range p.Profiles {
+		s := Score(prof.Name, search) - profilePenalty
+		if s > best.Score {
+			best = Match{Field: ProfileField, Value: prof.Name.Get(), Score: s}
+		}
+	}
+	if domain, ok := isDomainSearch(search); ok {
+		for _, r := range p.Realms() {
+			if s := scoreRealm(r, domain); s > best.Score {
+				best = Match{Field: RealmField, Value: r, Score: s}
+			}
+		}
+	}
+	return best
+}